@@ -2,21 +2,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
 	app2 "github.com/jva44ka/ozon-simulator-go-cart/internal/app"
 )
 
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
+// @description                 JWT в формате "Bearer <token>"; subject токена должен совпадать с user_id
 func main() {
 	fmt.Println("app starting")
 
 	app, err := app2.NewApp(os.Getenv("CONFIG_PATH"))
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, "app init failed:", err)
+		os.Exit(1)
 	}
 
-	if err := app.ListenAndServe(); err != nil {
-		panic(err)
+	if err := app.Run(context.Background()); err != nil {
+		fmt.Fprintln(os.Stderr, "app stopped with error:", err)
+		os.Exit(1)
 	}
 }
@@ -2,29 +2,114 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jva44ka/ozon-simulator-go-cart/docs"
 	"github.com/jva44ka/ozon-simulator-go-cart/internal/app/handlers/add_products_to_cart_handler"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/app/handlers/apply_cart_batch_handler"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/app/handlers/apply_promo_code_handler"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/app/handlers/checkout_handler"
 	"github.com/jva44ka/ozon-simulator-go-cart/internal/app/handlers/clean_cart_handler"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/app/handlers/decrease_product_count_handler"
 	"github.com/jva44ka/ozon-simulator-go-cart/internal/app/handlers/get_cart_items_by_user_id_handler"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/app/handlers/liveness_handler"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/app/handlers/readiness_handler"
 	"github.com/jva44ka/ozon-simulator-go-cart/internal/app/handlers/remove_products_from_cart_handler"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/app/handlers/remove_promo_code_handler"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/app/handlers/set_product_count_handler"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	httpSwagger "github.com/swaggo/http-swagger"
 
 	cartItemsRepositoryPkg "github.com/jva44ka/ozon-simulator-go-cart/internal/domain/cart_items/repository"
 	cartItemsServicePkg "github.com/jva44ka/ozon-simulator-go-cart/internal/domain/cart_items/service"
+	idempotencyRepositoryPkg "github.com/jva44ka/ozon-simulator-go-cart/internal/domain/idempotency/repository"
+	idempotencyServicePkg "github.com/jva44ka/ozon-simulator-go-cart/internal/domain/idempotency/service"
+	lomsServicePkg "github.com/jva44ka/ozon-simulator-go-cart/internal/domain/loms/service"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	outboxRepositoryPkg "github.com/jva44ka/ozon-simulator-go-cart/internal/domain/outbox/repository"
+	outboxServicePkg "github.com/jva44ka/ozon-simulator-go-cart/internal/domain/outbox/service"
 	productsServicePkg "github.com/jva44ka/ozon-simulator-go-cart/internal/domain/products/service"
+	promoServicePkg "github.com/jva44ka/ozon-simulator-go-cart/internal/domain/promo/service"
+	stocksServicePkg "github.com/jva44ka/ozon-simulator-go-cart/internal/domain/stocks/service"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/auth"
 	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/config"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/database"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/health"
 	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/http/middlewares"
 	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/http/round_trippers"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/kafka"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/metrics"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/tracing"
+	"github.com/jva44ka/ozon-simulator-go-cart/pkg/ratelimit"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"golang.org/x/time/rate"
 )
 
+const (
+	databaseDriverPostgres = "postgres"
+	databaseDriverMemory   = "memory"
+
+	defaultShutdownTimeout = 15 * time.Second
+)
+
+// idempotentRoutes маршруты, которые принимают заголовок Idempotency-Key
+var idempotentRoutes = map[string]struct{}{
+	"POST /user/{user_id}/cart/{sku_id}":    {},
+	"PUT /user/{user_id}/cart/{sku_id}":     {},
+	"PATCH /user/{user_id}/cart/{sku_id}":   {},
+	"DELETE /user/{user_id}/cart/{sku_id}":  {},
+	"DELETE /user/{user_id}/cart":           {},
+	"POST /user/{user_id}/cart/checkout":    {},
+	"POST /user/{user_id}/cart/items:batch": {},
+	"POST /user/{user_id}/cart/promo":       {},
+	"DELETE /user/{user_id}/cart/promo":     {},
+}
+
+type idempotencyRepository interface {
+	middlewares.IdempotencyStore
+	idempotencyServicePkg.IdempotencyRepository
+}
+
+type cartRepository interface {
+	cartItemsServicePkg.CartRepository
+	cartItemsServicePkg.ExpiredCartRepository
+}
+
+type outboxRepository interface {
+	cartItemsServicePkg.OutboxRepository
+	outboxServicePkg.OutboxRepository
+	outboxServicePkg.PublishedOutboxRepository
+}
+
 type App struct {
-	config *config.Config
-	server http.Server
+	config             *config.Config
+	log                *slog.Logger
+	tracerProvider     *sdktrace.TracerProvider
+	server             http.Server
+	pool               *pgxpool.Pool
+	producer           io.Closer
+	outboxRelay        *outboxServicePkg.Relay
+	outboxCleaner      *outboxServicePkg.Cleaner
+	idempotencyCleaner *idempotencyServicePkg.Cleaner
+	cartExpirer        *cartItemsServicePkg.CartExpirer
+	ready              atomic.Bool
+
+	productsCache *productsServicePkg.CachingProductService
 }
 
 func NewApp(configPath string) (*App, error) {
@@ -33,11 +118,34 @@ func NewApp(configPath string) (*App, error) {
 		return nil, fmt.Errorf("config.LoadConfig: %w", err)
 	}
 
+	log, err := logger.New(os.Stdout, configImpl.Log.Level, configImpl.Log.Format)
+	if err != nil {
+		return nil, fmt.Errorf("logger.New: %w", err)
+	}
+
+	tracerProvider, err := tracing.NewTracerProvider(
+		context.Background(),
+		configImpl.Tracing.Exporter,
+		configImpl.Tracing.Endpoint,
+		configImpl.Tracing.ServiceName,
+		os.Stdout,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tracing.NewTracerProvider: %w", err)
+	}
+
 	app := &App{
-		config: configImpl,
+		config:         configImpl,
+		log:            log,
+		tracerProvider: tracerProvider,
 	}
 
-	app.server.Handler, err = boostrapHandler(configImpl)
+	app.server.ReadTimeout = configImpl.Server.ReadTimeout
+	app.server.ReadHeaderTimeout = configImpl.Server.ReadHeaderTimeout
+	app.server.WriteTimeout = configImpl.Server.WriteTimeout
+	app.server.IdleTimeout = configImpl.Server.IdleTimeout
+
+	app.server.Handler, err = app.boostrapHandler()
 	if err != nil {
 		return nil, fmt.Errorf("boostrapHandler: %w", err)
 	}
@@ -45,52 +153,400 @@ func NewApp(configPath string) (*App, error) {
 	return app, nil
 }
 
-func (app *App) ListenAndServe() error {
+// Run обслуживает запросы до отмены ctx или получения SIGINT/SIGTERM, после чего плавно останавливает приложение
+func (app *App) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	address := fmt.Sprintf("%s:%s", app.config.Server.Host, app.config.Server.Port)
 
 	l, err := net.Listen("tcp", address)
 	if err != nil {
-		return err
+		return fmt.Errorf("net.Listen: %w", err)
+	}
+
+	return app.serve(ctx, l)
+}
+
+func (app *App) serve(ctx context.Context, l net.Listener) error {
+	workersCtx, cancelWorkers := context.WithCancel(logger.WithContext(context.Background(), app.log))
+	defer cancelWorkers()
+
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		app.outboxRelay.Run(logger.WithContext(workersCtx, app.log.With("worker", "outbox_relay")))
+	}()
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		app.outboxCleaner.Run(logger.WithContext(workersCtx, app.log.With("worker", "outbox_cleaner")))
+	}()
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		app.cartExpirer.Run(logger.WithContext(workersCtx, app.log.With("worker", "cart_expirer")))
+	}()
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		app.idempotencyCleaner.Run(logger.WithContext(workersCtx, app.log.With("worker", "idempotency_cleaner")))
+	}()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- app.server.Serve(l)
+	}()
+
+	app.ready.Store(true)
+	app.log.Info("server listening", "address", l.Addr().String())
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		app.log.Info("shutdown started")
+	case err := <-serveErr:
+		runErr = fmt.Errorf("server.Serve: %w", err)
+	}
+
+	// Сначала снимаем готовность, чтобы балансировщик перестал присылать новые запросы
+	app.ready.Store(false)
+	if runErr == nil && app.config.Server.ShutdownDelay > 0 {
+		time.Sleep(app.config.Server.ShutdownDelay)
+	}
+
+	shutdownTimeout := app.config.Server.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := app.server.Shutdown(shutdownCtx); err != nil {
+		runErr = errors.Join(runErr, fmt.Errorf("server.Shutdown: %w", err))
+	}
+
+	cancelWorkers()
+	workers.Wait()
+
+	// Producer закрывается после остановки outbox relay, чтобы не оборвать отправку
+	if app.producer != nil {
+		if err := app.producer.Close(); err != nil {
+			runErr = errors.Join(runErr, fmt.Errorf("producer.Close: %w", err))
+		}
 	}
 
-	return app.server.Serve(l)
+	if err := app.tracerProvider.Shutdown(shutdownCtx); err != nil {
+		runErr = errors.Join(runErr, fmt.Errorf("tracerProvider.Shutdown: %w", err))
+	}
+
+	if app.pool != nil {
+		app.pool.Close()
+	}
+
+	app.log.Info("shutdown completed")
+
+	return runErr
 }
 
-func boostrapHandler(config *config.Config) (http.Handler, error) {
+// cartRouteMiddleware возвращает обертку для маршрутов корзины: авторизация, ограничение частоты запросов,
+// идемпотентность и If-Match для изменяющих запросов
+func (app *App) cartRouteMiddleware(
+	idempotencyStore middlewares.IdempotencyStore,
+) (func(pattern string, h http.Handler) http.Handler, error) {
+	authConfig := app.config.Auth
+
+	var verifier *auth.Verifier
+	if authConfig.Enabled {
+		key, err := auth.LoadKey(authConfig.Key, authConfig.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("auth.LoadKey: %w", err)
+		}
+
+		verifier, err = auth.NewVerifier(authConfig.Algorithm, key, authConfig.Issuer, authConfig.Audience)
+		if err != nil {
+			return nil, fmt.Errorf("auth.NewVerifier: %w", err)
+		}
+	}
+
+	rateLimitConfig := app.config.RateLimit
+
+	// Общий лимит один на все маршруты корзины
+	var globalLimiter *rate.Limiter
+	if rateLimitConfig.Enabled && rateLimitConfig.Global.Rps > 0 {
+		globalLimiter = rate.NewLimiter(rate.Limit(rateLimitConfig.Global.Rps), max(rateLimitConfig.Global.Burst, 1))
+	}
+
+	return func(pattern string, h http.Handler) http.Handler {
+		if !strings.HasPrefix(pattern, http.MethodGet+" ") {
+			h = middlewares.NewIfMatchMiddleware(h)
+		}
+
+		if _, ok := idempotentRoutes[pattern]; ok {
+			h = middlewares.NewIdempotencyMiddleware(h, idempotencyStore, app.config.Idempotency.Ttl, app.config.Idempotency.LockTtl)
+		}
+
+		if rateLimitConfig.Enabled {
+			limit, ok := rateLimitConfig.Routes[pattern]
+			if !ok {
+				limit = rateLimitConfig.Default
+			}
+
+			var userLimiter *ratelimit.KeyedLimiter
+			if limit.Rps > 0 {
+				userLimiter = ratelimit.NewKeyedLimiter(limit.Rps, limit.Burst, rateLimitConfig.IdleTtl)
+			}
+
+			if userLimiter != nil || globalLimiter != nil {
+				h = middlewares.NewRateLimitMiddleware(h, userLimiter, globalLimiter)
+			}
+		}
+
+		if verifier != nil {
+			h = middlewares.NewAuthMiddleware(h, verifier, authConfig.ServiceRole)
+		}
+
+		return h
+	}, nil
+}
+
+func (app *App) boostrapHandler() (http.Handler, error) {
+	config := app.config
+
+	// Коллекторы, привязанные к ресурсам приложения, регистрируются в собственном реестре:
+	// в глобальном повторный NewApp паниковал бы из-за повторной регистрации
+	metricsRegistry := prometheus.NewRegistry()
+
 	tr := http.DefaultTransport
-	tr = round_trippers.NewTimerRoundTipper(tr)
+	tr = round_trippers.NewTracingRoundTripper(tr, "loms")
+	tr = round_trippers.NewTimerRoundTipper(tr, "loms", app.log)
 
-	client := http.Client{Transport: tr}
+	client := http.Client{Transport: tr, Timeout: config.Loms.Timeout}
 
-	productService := productsServicePkg.NewProductService(
-		client,
+	stocksTr := http.DefaultTransport
+	stocksTr = round_trippers.NewTracingRoundTripper(stocksTr, "stocks")
+	stocksTr = round_trippers.NewTimerRoundTipper(stocksTr, "stocks", app.log)
+
+	stocksClient := http.Client{Transport: stocksTr, Timeout: config.Stocks.Timeout}
+
+	productsTr := http.DefaultTransport
+	productsTr = round_trippers.NewRetryRoundTripper(
+		productsTr,
+		config.Products.Retry.MaxAttempts,
+		config.Products.Retry.BaseDelay,
+		config.Products.Retry.MaxDelay,
+		config.Products.Retry.AttemptTimeout,
+	)
+	if config.Products.CircuitBreaker.FailureThreshold > 0 {
+		productsTr = round_trippers.NewCircuitBreakerRoundTripper(
+			productsTr,
+			config.Products.CircuitBreaker.FailureThreshold,
+			config.Products.CircuitBreaker.OpenTimeout,
+		)
+	}
+	productsTr = round_trippers.NewTracingRoundTripper(productsTr, "products")
+	productsTr = round_trippers.NewTimerRoundTipper(productsTr, "products", app.log)
+
+	productsClient := http.Client{Transport: productsTr, Timeout: config.Products.Timeout}
+	productsAddress := fmt.Sprintf("%s://%s:%s", config.Products.Schema, config.Products.Host, config.Products.Port)
+
+	healthRegistry := health.NewRegistry(config.Health.CheckTimeout, config.Health.CacheTtl)
+	if config.Health.CheckProducts {
+		// Проверка идет мимо ретраев и circuit breaker, чтобы отражать текущее состояние сервиса
+		healthRegistry.Register(health.NewHttpChecker("products", http.Client{}, productsAddress))
+	}
+
+	var productService cartItemsServicePkg.ProductService = productsServicePkg.NewProductService(
+		productsClient,
 		config.Products.Token,
-		fmt.Sprintf("%s://%s:%s", config.Products.Schema, config.Products.Host, config.Products.Port),
+		productsAddress,
+		config.Products.BatchLookup,
+		config.Products.LookupConcurrency,
 	)
 
-	pool, err := pgxpool.New(context.Background(), fmt.Sprintf(
-		"postgres://%s:%s@%s:%s/%s",
-		config.Database.User,
-		config.Database.Password,
-		config.Database.Host,
-		config.Database.Port,
-		config.Database.Name,
-	))
+	if config.Products.CacheSize > 0 {
+		app.productsCache = productsServicePkg.NewCachingProductService(
+			productService,
+			config.Products.CacheSize,
+			config.Products.CacheTtl,
+			config.Products.CacheNotFoundTtl,
+		)
+		productService = app.productsCache
+
+		metricsRegistry.MustRegister(metrics.NewProductCacheCollector(func() (uint64, uint64, int) {
+			stats := app.productsCache.Stats()
+
+			return stats.Hits, stats.Misses, stats.Size
+		}))
+	}
+
+	lomsService := lomsServicePkg.NewLomsService(
+		client,
+		fmt.Sprintf("%s://%s:%s", config.Loms.Schema, config.Loms.Host, config.Loms.Port),
+	)
+
+	var stockService cartItemsServicePkg.StockService
+	switch config.Stocks.Driver {
+	case "memory":
+		stockService = stocksServicePkg.NewInMemoryStockService(config.Stocks.MemoryCount)
+	case "", "http":
+		stockService = stocksServicePkg.NewStockService(
+			stocksClient,
+			fmt.Sprintf("%s://%s:%s", config.Stocks.Schema, config.Stocks.Host, config.Stocks.Port),
+		)
+	default:
+		return nil, fmt.Errorf("unknown stocks driver %q", config.Stocks.Driver)
+	}
+
+	promoEngine, err := promoServicePkg.NewEngine(nil)
 	if err != nil {
-		return nil, fmt.Errorf("pgxpool.New: %w", err)
+		return nil, err
 	}
 
-	cartRepository := cartItemsRepositoryPkg.NewPgxCartItemRepository(pool)
-	cartService := cartItemsServicePkg.NewCartService(cartRepository, productService)
+	if config.Promo.RulesFile != "" {
+		promoEngine, err = promoServicePkg.LoadEngine(config.Promo.RulesFile)
+		if err != nil {
+			return nil, fmt.Errorf("promoServicePkg.LoadEngine: %w", err)
+		}
+	}
+
+	var (
+		transactor            outboxServicePkg.Transactor
+		cartRepository        cartRepository
+		outboxRepository      outboxRepository
+		idempotencyRepository idempotencyRepository
+	)
+
+	// Без брокеров события остаются в памяти процесса, это допустимо только вместе с in-memory базой
+	if config.Kafka.Brokers == "" && config.Database.Driver != databaseDriverMemory {
+		return nil, fmt.Errorf("kafka.brokers must be set for database driver %q", config.Database.Driver)
+	}
+
+	switch config.Database.Driver {
+	case databaseDriverMemory:
+		transactor = database.NewInMemoryTransactor()
+		cartRepository = cartItemsRepositoryPkg.NewInMemoryCartItemRepository()
+		outboxRepository = outboxRepositoryPkg.NewInMemoryOutboxRepository()
+		idempotencyRepository = idempotencyRepositoryPkg.NewInMemoryIdempotencyRepository()
+	case databaseDriverPostgres, "":
+		poolConfig, err := pgxpool.ParseConfig(fmt.Sprintf(
+			"postgres://%s:%s@%s:%s/%s",
+			config.Database.User,
+			config.Database.Password,
+			config.Database.Host,
+			config.Database.Port,
+			config.Database.Name,
+		))
+		if err != nil {
+			return nil, fmt.Errorf("pgxpool.ParseConfig: %w", err)
+		}
+
+		poolConfig.ConnConfig.Tracer = database.NewQueryTracer()
+
+		pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+		if err != nil {
+			return nil, fmt.Errorf("pgxpool.NewWithConfig: %w", err)
+		}
+		app.pool = pool
+
+		metricsRegistry.MustRegister(metrics.NewPgxPoolCollector(pool))
+		healthRegistry.Register(health.NewPgxPoolChecker("postgres", pool))
+
+		transactor = database.NewPgxTransactor(pool)
+		cartRepository = cartItemsRepositoryPkg.NewPgxCartItemRepository(pool)
+		outboxRepository = outboxRepositoryPkg.NewPgxOutboxRepository(pool)
+		idempotencyRepository = idempotencyRepositoryPkg.NewPgxIdempotencyRepository(pool)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", config.Database.Driver)
+	}
+
+	cartService := cartItemsServicePkg.NewCartService(
+		cartRepository,
+		productService,
+		stockService,
+		lomsService,
+		promoEngine,
+		outboxRepository,
+		transactor,
+		model.CartLimits{
+			MaxUnitsPerSku:  config.CartLimits.MaxUnitsPerSku,
+			MaxDistinctSkus: config.CartLimits.MaxDistinctSkus,
+			MaxTotalUnits:   config.CartLimits.MaxTotalUnits,
+		},
+	)
+
+	app.cartExpirer = cartItemsServicePkg.NewCartExpirer(
+		cartRepository,
+		outboxRepository,
+		transactor,
+		config.CartExpiration.Ttl,
+		config.CartExpiration.Interval,
+		config.CartExpiration.BatchSize,
+		nil,
+	)
+
+	var producer outboxServicePkg.Producer = kafka.NewInMemoryBroker()
+	if config.Kafka.Brokers != "" {
+		kafkaProducer := kafka.NewProducer(config.Kafka.Brokers)
+		app.producer = kafkaProducer
+		producer = kafkaProducer
+	}
+
+	app.outboxRelay = outboxServicePkg.NewRelay(
+		outboxRepository,
+		transactor,
+		producer,
+		config.Kafka.CartTopic,
+		config.Outbox.PollInterval,
+		config.Outbox.BatchSize,
+	)
+
+	app.outboxCleaner = outboxServicePkg.NewCleaner(
+		outboxRepository,
+		config.Outbox.Retention,
+		config.Outbox.CleanupInterval,
+		0,
+		nil,
+	)
+
+	app.idempotencyCleaner = idempotencyServicePkg.NewCleaner(idempotencyRepository, config.Idempotency.CleanupInterval)
+
+	cartRoute, err := app.cartRouteMiddleware(idempotencyRepository)
+	if err != nil {
+		return nil, fmt.Errorf("cartRouteMiddleware: %w", err)
+	}
 
 	mx := http.NewServeMux()
-	mx.Handle("GET /user/{user_id}/cart", get_cart_items_by_user_id_handler.NewGetCartItemsByUserIdHandler(cartService))
-	mx.Handle("POST /user/{user_id}/cart/{sku_id}", add_products_to_cart_handler.NewAddProductsToCartHandler(cartService))
-	mx.Handle("DELETE /user/{user_id}/cart/{sku_id}", remove_products_from_cart_handler.NewRemoveProductsFromCartHandler(cartService))
-	mx.Handle("DELETE /user/{user_id}/cart", clean_cart_handler.NewCleanCartHandler(cartService))
+	handleCart := func(pattern string, h http.Handler) {
+		mx.Handle(pattern, cartRoute(pattern, h))
+	}
+
+	handleCart("GET /user/{user_id}/cart", get_cart_items_by_user_id_handler.NewGetCartItemsByUserIdHandler(cartService))
+	handleCart("POST /user/{user_id}/cart/{sku_id}", add_products_to_cart_handler.NewAddProductsToCartHandler(cartService))
+	handleCart("PUT /user/{user_id}/cart/{sku_id}", set_product_count_handler.NewSetProductCountHandler(cartService))
+	handleCart("PATCH /user/{user_id}/cart/{sku_id}", decrease_product_count_handler.NewDecreaseProductCountHandler(cartService))
+	handleCart("DELETE /user/{user_id}/cart/{sku_id}", remove_products_from_cart_handler.NewRemoveProductsFromCartHandler(cartService))
+	handleCart("DELETE /user/{user_id}/cart", clean_cart_handler.NewCleanCartHandler(cartService))
+	handleCart("POST /user/{user_id}/cart/checkout", checkout_handler.NewCheckoutHandler(cartService))
+	handleCart("POST /user/{user_id}/cart/items:batch", apply_cart_batch_handler.NewApplyCartBatchHandler(cartService))
+	handleCart("POST /user/{user_id}/cart/promo", apply_promo_code_handler.NewApplyPromoCodeHandler(cartService))
+	handleCart("DELETE /user/{user_id}/cart/promo", remove_promo_code_handler.NewRemovePromoCodeHandler(cartService))
 	mx.Handle("/swagger/", httpSwagger.WrapHandler)
+	mx.Handle("GET /metrics", promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.Gatherers{prometheus.DefaultGatherer, metricsRegistry}, promhttp.HandlerOpts{}),
+	))
+	mx.Handle("GET /healthz", liveness_handler.NewLivenessHandler())
+	mx.Handle("GET /readyz", readiness_handler.NewReadinessHandler(healthRegistry, app.ready.Load))
 
-	middleware := middlewares.NewTimerMiddleware(mx)
+	var middleware http.Handler = middlewares.NewTracingMiddleware(mx)
+	middleware = middlewares.NewMetricsMiddleware(middleware)
+	middleware = middlewares.NewLoggingMiddleware(middleware, app.log)
 
 	return middleware, nil
 }
@@ -0,0 +1,247 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+server:
+  host: 127.0.0.1
+  port: 0
+  shutdown_delay: 300ms
+  shutdown_timeout: 5s
+
+log:
+  level: error
+
+tracing:
+  exporter: none
+
+products:
+  schema: http
+  host: 127.0.0.1
+  port: 1
+
+database:
+  driver: memory
+`
+
+func TestApp_GracefulShutdown(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "values.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(testConfig), 0o600))
+
+	app, err := NewApp(configPath)
+	require.NoError(t, err)
+
+	// Медленный обработчик имитирует запрос, который должен завершиться во время остановки
+	started := make(chan struct{})
+	handler := app.server.Handler
+	mx := http.NewServeMux()
+	mx.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(500 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+	mx.Handle("/", handler)
+	app.server.Handler = mx
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := "http://" + l.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- app.serve(ctx, l)
+	}()
+
+	require.Eventually(t, func() bool {
+		response, err := http.Get(address + "/readyz")
+		if err != nil {
+			return false
+		}
+		response.Body.Close()
+
+		return response.StatusCode == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+
+	response, err := http.Get(address + "/healthz")
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	response, err = http.Get(address + "/readyz")
+	require.NoError(t, err)
+	var readiness struct {
+		Status string `json:"status"`
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&readiness))
+	response.Body.Close()
+	require.Equal(t, "ok", readiness.Status)
+
+	slowStatus := make(chan int, 1)
+	go func() {
+		response, err := http.Get(address + "/slow")
+		if err != nil {
+			slowStatus <- 0
+			return
+		}
+		response.Body.Close()
+		slowStatus <- response.StatusCode
+	}()
+	<-started
+
+	cancel()
+
+	// До закрытия слушателя сервер продолжает отвечать, но уже не готов
+	require.Eventually(t, func() bool {
+		response, err := http.Get(address + "/readyz")
+		if err != nil {
+			return false
+		}
+		response.Body.Close()
+
+		return response.StatusCode == http.StatusServiceUnavailable
+	}, 250*time.Millisecond, 10*time.Millisecond)
+
+	require.Equal(t, http.StatusOK, <-slowStatus)
+
+	select {
+	case err := <-serveErr:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("app did not stop")
+	}
+
+	_, err = http.Get(address + "/readyz")
+	require.Error(t, err)
+}
+
+func TestApp_CartETag(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "values.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(testConfig), 0o600))
+
+	app, err := NewApp(configPath)
+	require.NoError(t, err)
+
+	cartUrl := "/user/" + uuid.NewString() + "/cart"
+
+	serve := func(method string, header string, value string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, cartUrl, nil)
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+
+		w := httptest.NewRecorder()
+		app.server.Handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	get := serve(http.MethodGet, "", "")
+	require.Equal(t, http.StatusOK, get.Code)
+	etag := get.Header().Get("ETag")
+	require.Equal(t, `"0"`, etag)
+
+	notModified := serve(http.MethodGet, "If-None-Match", etag)
+	require.Equal(t, http.StatusNotModified, notModified.Code)
+	require.Equal(t, etag, notModified.Header().Get("ETag"))
+	require.Empty(t, notModified.Body.String())
+
+	require.Equal(t, http.StatusPreconditionFailed, serve(http.MethodDelete, "If-Match", `"7"`).Code)
+	require.Equal(t, http.StatusOK, serve(http.MethodDelete, "If-Match", etag).Code)
+}
+
+func TestApp_PromoCodeRoutes(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "values.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(testConfig), 0o600))
+
+	app, err := NewApp(configPath)
+	require.NoError(t, err)
+
+	promoUrl := "/user/" + uuid.NewString() + "/cart/promo"
+
+	// Без файла правил ни один промокод не действует
+	r := httptest.NewRequest(http.MethodPost, promoUrl, strings.NewReader(`{"code":"WELCOME10"}`))
+	w := httptest.NewRecorder()
+	app.server.Handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Contains(t, w.Body.String(), "promo_code_not_found")
+
+	r = httptest.NewRequest(http.MethodDelete, promoUrl, nil)
+	w = httptest.NewRecorder()
+	app.server.Handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestApp_SetProductCountRequiresCount(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "values.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(testConfig), 0o600))
+
+	app, err := NewApp(configPath)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPut, "/user/"+uuid.NewString()+"/cart/10", strings.NewReader(`{}`))
+	w := httptest.NewRecorder()
+	app.server.Handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "count is required")
+}
+
+func TestApp_MetricsRouteLabel(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "values.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(testConfig), 0o600))
+
+	app, err := NewApp(configPath)
+	require.NoError(t, err)
+
+	const route = "GET /user/{user_id}/cart"
+	before := testutil.ToFloat64(metrics.HttpRequestsTotal.WithLabelValues(http.MethodGet, route, "200"))
+
+	w := httptest.NewRecorder()
+	app.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/"+uuid.NewString()+"/cart", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.Equal(t, before+1, testutil.ToFloat64(metrics.HttpRequestsTotal.WithLabelValues(http.MethodGet, route, "200")))
+}
+
+func TestNewApp_Twice(t *testing.T) {
+	config := strings.Replace(testConfig, "  port: 1\n", "  port: 1\n  cache_size: 10\n", 1)
+	configPath := filepath.Join(t.TempDir(), "values.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(config), 0o600))
+
+	// Коллекторы приложения не должны конфликтовать с коллекторами предыдущего экземпляра
+	for i := 0; i < 2; i++ {
+		app, err := NewApp(configPath)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		app.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), "cart_products_cache_size")
+		require.Contains(t, w.Body.String(), "cart_items_added_total")
+	}
+}
+
+func TestNewApp_KafkaBrokersRequired(t *testing.T) {
+	config := strings.Replace(testConfig, "driver: memory", "driver: postgres", 1)
+	configPath := filepath.Join(t.TempDir(), "values.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(config), 0o600))
+
+	_, err := NewApp(configPath)
+	require.ErrorContains(t, err, "kafka.brokers")
+}
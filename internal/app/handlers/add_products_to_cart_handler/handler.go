@@ -3,11 +3,12 @@ package add_products_to_cart_handler
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/metrics"
 	httpPkg "github.com/jva44ka/ozon-simulator-go-cart/pkg/http"
 )
 
@@ -32,11 +33,20 @@ func NewAddProductsToCartHandler(cartService CartService) *AddProductsToCartHand
 // @Tags         cart
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        user_id  path  string  true  "Токен пользователя"
+// @Param        If-Match  header  string  false  "ETag версии корзины, к которой применяется изменение"
 // @Param        sku_id   path  uint64  true  "SKU товара"
 // @Param        body     body  AddProductToCartRequest  true  "Тело запроса с количеством товаров"
+// @Param        Idempotency-Key  header  string  false  "Ключ идемпотентности: повтор с тем же ключом вернет сохраненный ответ"
 // @Success      200  {object}  AddProductToCartResponse
+// @Failure      400  {object}  httpPkg.ErrorResponse
 // @Failure      404  {object}  httpPkg.ErrorResponse
+// @Failure      503  {object}  httpPkg.ErrorResponse
+// @Failure      401  {object}  httpPkg.ErrorResponse
+// @Failure      403  {object}  httpPkg.ErrorResponse
+// @Failure      422  {object}  httpPkg.ErrorResponse
+// @Failure      412  {object}  httpPkg.ErrorResponse
 // @Router       /user/{user_id}/cart/{sku_id} [post]
 func (h *AddProductsToCartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
@@ -45,7 +55,7 @@ func (h *AddProductsToCartHandler) ServeHTTP(w http.ResponseWriter, r *http.Requ
 	sku, err := strconv.Atoi(skuRaw)
 	if err != nil {
 		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "sku must be more than zero"); err != nil {
-			fmt.Println("json.Encode failed ", err)
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
 
 			return
 		}
@@ -55,7 +65,7 @@ func (h *AddProductsToCartHandler) ServeHTTP(w http.ResponseWriter, r *http.Requ
 
 	if sku < 1 {
 		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "sku must be more than zero"); err != nil {
-			fmt.Println("json.Encode failed ", err)
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
 
 			return
 		}
@@ -67,7 +77,7 @@ func (h *AddProductsToCartHandler) ServeHTTP(w http.ResponseWriter, r *http.Requ
 	userId, err := uuid.Parse(userIdRaw)
 	if err != nil {
 		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "user_id must be valid uuid"); err != nil {
-			fmt.Println("json.Encode failed ", err)
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
 
 			return
 		}
@@ -79,7 +89,7 @@ func (h *AddProductsToCartHandler) ServeHTTP(w http.ResponseWriter, r *http.Requ
 
 	if err = json.NewDecoder(r.Body).Decode(&request); err != nil {
 		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, err.Error()); err != nil {
-			fmt.Println("json.Encode failed ", err)
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
 
 			return
 		}
@@ -89,15 +99,17 @@ func (h *AddProductsToCartHandler) ServeHTTP(w http.ResponseWriter, r *http.Requ
 
 	err = h.cartService.AddProduct(r.Context(), userId, uint64(sku), request.Count)
 	if err != nil {
-		if err = httpPkg.NewErrorResponse(w, http.StatusInternalServerError, err.Error()); err != nil {
+		if err = httpPkg.WriteError(w, err); err != nil {
 			return
 		}
 
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	metrics.CartItemsAddedTotal.Add(float64(request.Count))
+
 	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 
 	return
 }
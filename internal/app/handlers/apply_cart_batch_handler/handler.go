@@ -0,0 +1,155 @@
+package apply_cart_batch_handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/metrics"
+	httpPkg "github.com/jva44ka/ozon-simulator-go-cart/pkg/http"
+)
+
+type CartService interface {
+	ApplyBatch(
+		ctx context.Context,
+		userId uuid.UUID,
+		operations []model.CartItemOperation,
+		mode model.BatchMode,
+	) ([]model.CartItemOperationResult, error)
+}
+
+type ApplyCartBatchHandler struct {
+	cartService CartService
+}
+
+func NewApplyCartBatchHandler(cartService CartService) *ApplyCartBatchHandler {
+	return &ApplyCartBatchHandler{cartService: cartService}
+}
+
+// @Summary      Пакетно изменить корзину
+// @Description  Метод добавляет или удаляет несколько товаров корзины пользователя в одной транзакции.
+// Существование добавляемых товаров проверяется параллельно.
+// В режиме all_or_nothing при ошибке хотя бы одной операции не применяется ни одна, и возвращается 422 код ответа.
+// В режиме best_effort применяются все корректные операции.
+// Результат возвращается по каждой операции.
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id  path  string  true  "Токен пользователя"
+// @Param        If-Match  header  string  false  "ETag версии корзины, к которой применяется изменение"
+// @Param        body     body  ApplyCartBatchRequest  true  "Список операций"
+// @Success      200  {object}  ApplyCartBatchResponse
+// @Failure      400  {object}  httpPkg.ErrorResponse
+// @Failure      422  {object}  ApplyCartBatchResponse
+// @Failure      401  {object}  httpPkg.ErrorResponse
+// @Failure      403  {object}  httpPkg.ErrorResponse
+// @Failure      412  {object}  httpPkg.ErrorResponse
+// @Router       /user/{user_id}/cart/items:batch [post]
+func (h *ApplyCartBatchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	userIdRaw := r.PathValue("user_id")
+	userId, err := uuid.Parse(userIdRaw)
+	if err != nil {
+		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "user_id must be valid uuid"); err != nil {
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
+
+			return
+		}
+
+		return
+	}
+
+	var request ApplyCartBatchRequest
+
+	if err = json.NewDecoder(r.Body).Decode(&request); err != nil {
+		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, err.Error()); err != nil {
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
+
+			return
+		}
+
+		return
+	}
+
+	mode := model.BatchMode(request.Mode)
+	if mode == "" {
+		mode = model.BatchModeAllOrNothing
+	}
+
+	operations := make([]model.CartItemOperation, 0, len(request.Items))
+	for _, item := range request.Items {
+		action := model.CartItemAction(item.Action)
+		if action == "" {
+			action = model.CartItemActionAdd
+		}
+
+		operations = append(operations, model.CartItemOperation{
+			Action: action,
+			SkuId:  item.Sku,
+			Count:  item.Count,
+		})
+	}
+
+	results, err := h.cartService.ApplyBatch(r.Context(), userId, operations, mode)
+	if err != nil && !errors.Is(err, model.ErrBatchNotApplied) {
+		if err = httpPkg.WriteError(w, err); err != nil {
+			return
+		}
+
+		return
+	}
+
+	response := ApplyCartBatchResponse{
+		Applied: err == nil,
+		Items:   make([]CartBatchItemResponse, 0, len(results)),
+	}
+	for _, result := range results {
+		item := CartBatchItemResponse{
+			Sku:    result.Operation.SkuId,
+			Count:  result.Operation.Count,
+			Action: string(result.Operation.Action),
+			Status: ItemStatusApplied,
+		}
+
+		switch {
+		case result.Err != nil:
+			_, errorResponse := httpPkg.MapError(result.Err)
+			item.Status = ItemStatusFailed
+			item.Code = errorResponse.Code
+			item.Error = errorResponse.Message
+		case !result.Applied:
+			item.Status = ItemStatusNotApplied
+		default:
+			countAppliedOperation(result.Operation)
+		}
+
+		response.Items = append(response.Items, item)
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	if !response.Applied {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	if err := json.NewEncoder(w).Encode(&response); err != nil {
+		logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
+		return
+	}
+
+	return
+}
+
+func countAppliedOperation(operation model.CartItemOperation) {
+	switch operation.Action {
+	case model.CartItemActionAdd:
+		metrics.CartItemsAddedTotal.Add(float64(operation.Count))
+	case model.CartItemActionRemove:
+		metrics.CartItemsRemovedTotal.Inc()
+	}
+}
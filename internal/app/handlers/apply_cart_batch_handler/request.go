@@ -0,0 +1,14 @@
+package apply_cart_batch_handler
+
+type ApplyCartBatchRequest struct {
+	// all_or_nothing (по умолчанию) или best_effort
+	Mode  string                 `json:"mode"`
+	Items []CartBatchItemRequest `json:"items"`
+}
+
+type CartBatchItemRequest struct {
+	Sku   uint64 `json:"sku"`
+	Count uint32 `json:"count"`
+	// add (по умолчанию) или remove
+	Action string `json:"action"`
+}
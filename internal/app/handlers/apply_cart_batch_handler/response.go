@@ -0,0 +1,21 @@
+package apply_cart_batch_handler
+
+const (
+	ItemStatusApplied    = "applied"
+	ItemStatusFailed     = "failed"
+	ItemStatusNotApplied = "not_applied"
+)
+
+type ApplyCartBatchResponse struct {
+	Applied bool                    `json:"applied"`
+	Items   []CartBatchItemResponse `json:"items"`
+}
+
+type CartBatchItemResponse struct {
+	Sku    uint64 `json:"sku"`
+	Count  uint32 `json:"count"`
+	Action string `json:"action"`
+	Status string `json:"status"`
+	Code   string `json:"code,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
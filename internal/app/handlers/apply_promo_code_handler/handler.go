@@ -0,0 +1,86 @@
+package apply_promo_code_handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+	httpPkg "github.com/jva44ka/ozon-simulator-go-cart/pkg/http"
+)
+
+type CartService interface {
+	ApplyPromoCode(ctx context.Context, userId uuid.UUID, code string) error
+}
+
+type ApplyPromoCodeHandler struct {
+	cartService CartService
+}
+
+func NewApplyPromoCodeHandler(cartService CartService) *ApplyPromoCodeHandler {
+	return &ApplyPromoCodeHandler{cartService: cartService}
+}
+
+// @Summary      Применить промокод к корзине
+// @Description  Метод применяет промокод к корзине пользователя, заменяя ранее примененный.
+// Если промокода нет в правилах, возвращается 404, если не выполнены его условия (например, минимальная сумма корзины) — 422.
+// Скидки по промокоду отображаются в ответе на получение корзины.
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id  path  string  true  "Токен пользователя"
+// @Param        If-Match  header  string  false  "ETag версии корзины, к которой применяется изменение"
+// @Param        body     body  ApplyPromoCodeRequest  true  "Тело запроса с промокодом"
+// @Param        Idempotency-Key  header  string  false  "Ключ идемпотентности: повтор с тем же ключом вернет сохраненный ответ"
+// @Success      200  {object}  ApplyPromoCodeResponse
+// @Failure      400  {object}  httpPkg.ErrorResponse
+// @Failure      404  {object}  httpPkg.ErrorResponse
+// @Failure      503  {object}  httpPkg.ErrorResponse
+// @Failure      401  {object}  httpPkg.ErrorResponse
+// @Failure      403  {object}  httpPkg.ErrorResponse
+// @Failure      422  {object}  httpPkg.ErrorResponse
+// @Failure      412  {object}  httpPkg.ErrorResponse
+// @Router       /user/{user_id}/cart/promo [post]
+func (h *ApplyPromoCodeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	userIdRaw := r.PathValue("user_id")
+	userId, err := uuid.Parse(userIdRaw)
+	if err != nil {
+		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "user_id must be valid uuid"); err != nil {
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
+
+			return
+		}
+
+		return
+	}
+
+	var request ApplyPromoCodeRequest
+
+	if err = json.NewDecoder(r.Body).Decode(&request); err != nil {
+		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, err.Error()); err != nil {
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
+
+			return
+		}
+
+		return
+	}
+
+	err = h.cartService.ApplyPromoCode(r.Context(), userId, request.Code)
+	if err != nil {
+		if err = httpPkg.WriteError(w, err); err != nil {
+			return
+		}
+
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	return
+}
@@ -0,0 +1,5 @@
+package apply_promo_code_handler
+
+type ApplyPromoCodeRequest struct {
+	Code string `json:"code"`
+}
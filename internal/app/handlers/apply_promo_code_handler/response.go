@@ -0,0 +1,3 @@
+package apply_promo_code_handler
+
+type ApplyPromoCodeResponse struct{}
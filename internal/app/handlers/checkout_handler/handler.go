@@ -0,0 +1,86 @@
+package checkout_handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/metrics"
+	httpPkg "github.com/jva44ka/ozon-simulator-go-cart/pkg/http"
+)
+
+const (
+	checkoutResultSucceeded = "succeeded"
+	checkoutResultFailed    = "failed"
+)
+
+type CartService interface {
+	Checkout(ctx context.Context, userId uuid.UUID) (int64, error)
+}
+
+type CheckoutHandler struct {
+	cartService CartService
+}
+
+func NewCheckoutHandler(cartService CartService) *CheckoutHandler {
+	return &CheckoutHandler{cartService: cartService}
+}
+
+// @Summary      Оформить заказ
+// @Description  Метод создает заказ в сервисе LOMS из всех товаров корзины пользователя.
+// Корзина очищается только после успешного создания заказа.
+// Если корзина пуста, возвращается 404 код ответа.
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id  path  string  true  "Токен пользователя"
+// @Param        If-Match  header  string  false  "ETag версии корзины, к которой применяется изменение"
+// @Param        Idempotency-Key  header  string  false  "Ключ идемпотентности: повтор с тем же ключом вернет сохраненный ответ"
+// @Success      200  {object}  CheckoutResponse
+// @Failure      404  {object}  httpPkg.ErrorResponse
+// @Failure      503  {object}  httpPkg.ErrorResponse
+// @Failure      401  {object}  httpPkg.ErrorResponse
+// @Failure      403  {object}  httpPkg.ErrorResponse
+// @Failure      422  {object}  httpPkg.ErrorResponse
+// @Failure      412  {object}  httpPkg.ErrorResponse
+// @Failure      409  {object}  httpPkg.ErrorResponse
+// @Router       /user/{user_id}/cart/checkout [post]
+func (h *CheckoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	userIdRaw := r.PathValue("user_id")
+	userId, err := uuid.Parse(userIdRaw)
+	if err != nil {
+		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "user_id must be valid uuid"); err != nil {
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
+
+			return
+		}
+
+		return
+	}
+
+	orderId, err := h.cartService.Checkout(r.Context(), userId)
+	if err != nil {
+		metrics.CheckoutsTotal.WithLabelValues(checkoutResultFailed).Inc()
+
+		if err = httpPkg.WriteError(w, err); err != nil {
+			return
+		}
+
+		return
+	}
+
+	metrics.CheckoutsTotal.WithLabelValues(checkoutResultSucceeded).Inc()
+
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&CheckoutResponse{OrderId: orderId}); err != nil {
+		logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
+		return
+	}
+
+	return
+}
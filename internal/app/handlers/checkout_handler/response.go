@@ -0,0 +1,5 @@
+package checkout_handler
+
+type CheckoutResponse struct {
+	OrderId int64 `json:"order_id"`
+}
@@ -2,10 +2,11 @@ package clean_cart_handler
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 
 	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/metrics"
 	httpPkg "github.com/jva44ka/ozon-simulator-go-cart/pkg/http"
 )
 
@@ -27,9 +28,16 @@ func NewCleanCartHandler(cartService CartService) *CleanCartHandler {
 // @Tags         cart
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        user_id  path  string  true  "Токен пользователя"
+// @Param        If-Match  header  string  false  "ETag версии корзины, к которой применяется изменение"
+// @Param        Idempotency-Key  header  string  false  "Ключ идемпотентности: повтор с тем же ключом вернет сохраненный ответ"
 // @Success      200  {object}  CleanCartResponse
 // @Failure      404  {object}  httpPkg.ErrorResponse
+// @Failure      401  {object}  httpPkg.ErrorResponse
+// @Failure      403  {object}  httpPkg.ErrorResponse
+// @Failure      422  {object}  httpPkg.ErrorResponse
+// @Failure      412  {object}  httpPkg.ErrorResponse
 // @Router       /user/{user_id}/cart [delete]
 func (h *CleanCartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
@@ -38,7 +46,7 @@ func (h *CleanCartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	userId, err := uuid.Parse(userIdRaw)
 	if err != nil {
 		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "user_id must be valid uuid"); err != nil {
-			fmt.Println("json.Encode failed ", err)
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
 
 			return
 		}
@@ -48,15 +56,17 @@ func (h *CleanCartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	err = h.cartService.RemoveAllProducts(r.Context(), userId)
 	if err != nil {
-		if err = httpPkg.NewErrorResponse(w, http.StatusInternalServerError, err.Error()); err != nil {
+		if err = httpPkg.WriteError(w, err); err != nil {
 			return
 		}
 
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	metrics.CartsClearedTotal.Inc()
+
 	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 
 	return
 }
@@ -0,0 +1,5 @@
+package decrease_product_count_handler
+
+type DecreaseProductCountRequest struct {
+	Count uint32 `json:"count"`
+}
@@ -0,0 +1,3 @@
+package decrease_product_count_handler
+
+type DecreaseProductCountResponse struct{}
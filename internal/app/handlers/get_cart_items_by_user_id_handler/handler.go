@@ -3,16 +3,18 @@ package get_cart_items_by_user_id_handler
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
+	"slices"
 
 	"github.com/google/uuid"
 	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
 	httpPkg "github.com/jva44ka/ozon-simulator-go-cart/pkg/http"
 )
 
 type CartService interface {
-	GetItemsByUserId(ctx context.Context, userId uuid.UUID) ([]model.CartItem, error)
+	GetCart(ctx context.Context, userId uuid.UUID) (*model.Cart, error)
+	GetCartVersion(ctx context.Context, userId uuid.UUID) (uint64, error)
 }
 
 type GetReviewsBySkuHandler struct {
@@ -27,19 +29,28 @@ func NewGetCartItemsByUserIdHandler(cartService CartService) *GetReviewsBySkuHan
 // @Description  Метод возвращает содержимое корзины пользователя на текущий момент.
 // Если корзины у переданного пользователя нет, либо она пуста, следует вернуть 404 код ответа.
 // Товары в корзине упорядочены в порядке возрастания sku.
+// Для каждого товара возвращаются название, цена за единицу и стоимость позиции, а также общая стоимость корзины.
+// Если к корзине применен промокод, возвращаются сумма без скидок, начисленные скидки и итоговая сумма.
+// Версия корзины возвращается в заголовке ETag. Если она совпадает с If-None-Match, возвращается 304 без тела.
 // @Tags         cart
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        user_id  path  string  true  "Токен пользователя"
-// @Success      200  {object}  CartItemResponse
+// @Param        If-None-Match  header  string  false  "ETag ранее полученной версии корзины"
+// @Success      200  {object}  GetReviewsResponse
+// @Success      304
 // @Failure      404  {object}  httpPkg.ErrorResponse
+// @Failure      503  {object}  httpPkg.ErrorResponse
+// @Failure      401  {object}  httpPkg.ErrorResponse
+// @Failure      403  {object}  httpPkg.ErrorResponse
 // @Router       /user/{user_id}/cart [get]
 func (h *GetReviewsBySkuHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	userIdRaw := r.PathValue("user_id")
 	userId, err := uuid.Parse(userIdRaw)
 	if err != nil {
 		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "user_id must be valid uuid"); err != nil {
-			fmt.Println("json.Encode failed ", err)
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
 
 			return
 		}
@@ -49,7 +60,7 @@ func (h *GetReviewsBySkuHandler) ServeHTTP(w http.ResponseWriter, r *http.Reques
 
 	if userId == uuid.Nil {
 		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "userId must be not Nil"); err != nil {
-			fmt.Println("json.Encode failed ", err)
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
 
 			return
 		}
@@ -57,28 +68,70 @@ func (h *GetReviewsBySkuHandler) ServeHTTP(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	cartItems, err := h.cartService.GetItemsByUserId(r.Context(), userId)
+	// Неизменившуюся корзину отдаем без обращения к сервису товаров
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		version, err := h.cartService.GetCartVersion(r.Context(), userId)
+		if err != nil {
+			if err = httpPkg.WriteError(w, err); err != nil {
+				return
+			}
+
+			return
+		}
+
+		versions, matchAny := httpPkg.ParseETags(ifNoneMatch, true)
+		if matchAny || slices.Contains(versions, version) {
+			w.Header().Set("ETag", httpPkg.FormatETag(version))
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+	}
+
+	cart, err := h.cartService.GetCart(r.Context(), userId)
 	if err != nil {
-		if err = httpPkg.NewErrorResponse(w, http.StatusInternalServerError, err.Error()); err != nil {
+		if err = httpPkg.WriteError(w, err); err != nil {
 			return
 		}
 
 		return
 	}
 
-	response := GetReviewsResponse{CartItems: make([]CartItemResponse, 0, len(cartItems))}
-	for _, cartItem := range cartItems {
+	response := GetReviewsResponse{
+		CartItems:  make([]CartItemResponse, 0, len(cart.Items)),
+		Subtotal:   cart.Subtotal,
+		PromoCode:  cart.PromoCode,
+		Discounts:  make([]DiscountResponse, 0, len(cart.Discounts)),
+		TotalPrice: cart.TotalPrice,
+	}
+	for _, discount := range cart.Discounts {
+		response.Discounts = append(response.Discounts, DiscountResponse{
+			Code:   discount.Code,
+			Rule:   string(discount.Rule),
+			SkuId:  discount.SkuId,
+			Amount: discount.Amount,
+		})
+	}
+	for _, cartItem := range cart.Items {
 		response.CartItems = append(response.CartItems, CartItemResponse{
-			Id:     cartItem.Id,
-			SkuId:  cartItem.SkuId,
-			UserId: cartItem.UserId,
-			Count:  cartItem.Count,
+			Id:         cartItem.Id,
+			SkuId:      cartItem.SkuId,
+			UserId:     cartItem.UserId,
+			Count:      cartItem.Count,
+			Name:       cartItem.Name,
+			Price:      cartItem.Price,
+			TotalPrice: cartItem.TotalPrice,
+			OutOfStock: cartItem.OutOfStock,
+
+			StockUnknown:       cartItem.StockUnknown,
+			ProductUnavailable: cartItem.ProductUnavailable,
 		})
 	}
 
 	w.Header().Add("Content-Type", "application/json")
+	w.Header().Set("ETag", httpPkg.FormatETag(cart.Version))
 	if err := json.NewEncoder(w).Encode(&response); err != nil {
-		fmt.Println("success status failed")
+		logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
 		return
 	}
 
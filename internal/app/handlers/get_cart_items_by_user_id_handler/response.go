@@ -4,11 +4,31 @@ import "github.com/google/uuid"
 
 type GetReviewsResponse struct {
 	CartItems []CartItemResponse `json:"cart_items"`
+	Subtotal  float64            `json:"subtotal"`
+	PromoCode string             `json:"promo_code,omitempty"`
+	Discounts []DiscountResponse `json:"discounts"`
+	// TotalPrice итоговая сумма с учетом скидок
+	TotalPrice float64 `json:"total_price"`
+}
+
+type DiscountResponse struct {
+	Code   string  `json:"code"`
+	Rule   string  `json:"rule"`
+	SkuId  uint64  `json:"sku_id,omitempty"`
+	Amount float64 `json:"amount"`
 }
 
 type CartItemResponse struct {
-	Id     uint64    `json:"id"`
-	SkuId  uint64    `json:"sku_id"`
-	UserId uuid.UUID `json:"user_id"`
-	Count  uint32    `json:"count"`
+	Id         uint64    `json:"id"`
+	SkuId      uint64    `json:"sku_id"`
+	UserId     uuid.UUID `json:"user_id"`
+	Count      uint32    `json:"count"`
+	Name       string    `json:"name"`
+	Price      float64   `json:"price"`
+	TotalPrice float64   `json:"total_price"`
+	OutOfStock bool      `json:"out_of_stock"`
+	// StockUnknown остаток товара сейчас не удалось узнать
+	StockUnknown bool `json:"stock_unknown"`
+	// ProductUnavailable товар не удалось получить из каталога, имя и цена не заполнены
+	ProductUnavailable bool `json:"product_unavailable"`
 }
@@ -0,0 +1,32 @@
+package liveness_handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/health"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+)
+
+type LivenessHandler struct{}
+
+func NewLivenessHandler() *LivenessHandler {
+	return &LivenessHandler{}
+}
+
+// @Summary      Проверка живости
+// @Description  Отвечает 200, пока процесс способен обслуживать запросы. Зависимости не проверяются.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  LivenessResponse
+// @Router       /healthz [get]
+func (h *LivenessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(LivenessResponse{Status: health.StatusOk}); err != nil {
+		logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
+	}
+}
@@ -0,0 +1,5 @@
+package liveness_handler
+
+type LivenessResponse struct {
+	Status string `json:"status"`
+}
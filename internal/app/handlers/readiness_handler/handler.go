@@ -0,0 +1,57 @@
+package readiness_handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/health"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+)
+
+type HealthChecker interface {
+	Check(ctx context.Context) health.Report
+}
+
+type ReadinessHandler struct {
+	healthChecker HealthChecker
+	ready         func() bool
+}
+
+// NewReadinessHandler ready сообщает, принимает ли приложение трафик; во время остановки он возвращает false
+func NewReadinessHandler(healthChecker HealthChecker, ready func() bool) *ReadinessHandler {
+	return &ReadinessHandler{
+		healthChecker: healthChecker,
+		ready:         ready,
+	}
+}
+
+// @Summary      Проверка готовности
+// @Description  Проверяет зависимости сервиса. Возвращает 503, если хотя бы одна из них недоступна или приложение останавливается.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  ReadinessResponse
+// @Failure      503  {object}  ReadinessResponse
+// @Router       /readyz [get]
+func (h *ReadinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var response ReadinessResponse
+	if h.ready() {
+		response = newReadinessResponse(h.healthChecker.Check(r.Context()))
+	} else {
+		response = ReadinessResponse{Status: health.StatusFail, Checks: []CheckResponse{}}
+	}
+
+	status := http.StatusOK
+	if response.Status != health.StatusOk {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
+	}
+}
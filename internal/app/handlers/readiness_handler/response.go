@@ -0,0 +1,35 @@
+package readiness_handler
+
+import (
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/health"
+)
+
+type ReadinessResponse struct {
+	Status string          `json:"status"`
+	Checks []CheckResponse `json:"checks"`
+}
+
+type CheckResponse struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+func newReadinessResponse(report health.Report) ReadinessResponse {
+	response := ReadinessResponse{
+		Status: report.Status,
+		Checks: make([]CheckResponse, 0, len(report.Checks)),
+	}
+
+	for _, check := range report.Checks {
+		response.Checks = append(response.Checks, CheckResponse{
+			Name:      check.Name,
+			Status:    check.Status,
+			LatencyMs: float64(check.Latency.Microseconds()) / 1000,
+			Error:     check.Error,
+		})
+	}
+
+	return response
+}
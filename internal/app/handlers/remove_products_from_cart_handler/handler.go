@@ -2,11 +2,12 @@ package remove_products_from_cart_handler
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/metrics"
 	httpPkg "github.com/jva44ka/ozon-simulator-go-cart/pkg/http"
 )
 
@@ -28,10 +29,17 @@ func NewRemoveProductsFromCartHandler(cartService CartService) *RemoveProductsFr
 // @Tags         cart
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        user_id  path  string  true  "Токен пользователя"
+// @Param        If-Match  header  string  false  "ETag версии корзины, к которой применяется изменение"
 // @Param        sku_id   path  uint64  true  "SKU товара"
+// @Param        Idempotency-Key  header  string  false  "Ключ идемпотентности: повтор с тем же ключом вернет сохраненный ответ"
 // @Success      200  {object}  RemoveProductsFromCartResponse
 // @Failure      404  {object}  httpPkg.ErrorResponse
+// @Failure      401  {object}  httpPkg.ErrorResponse
+// @Failure      403  {object}  httpPkg.ErrorResponse
+// @Failure      422  {object}  httpPkg.ErrorResponse
+// @Failure      412  {object}  httpPkg.ErrorResponse
 // @Router       /user/{user_id}/cart/{sku_id} [delete]
 func (h *RemoveProductsFromCartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
@@ -40,7 +48,7 @@ func (h *RemoveProductsFromCartHandler) ServeHTTP(w http.ResponseWriter, r *http
 	sku, err := strconv.Atoi(skuRaw)
 	if err != nil {
 		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "sku must be more than zero"); err != nil {
-			fmt.Println("json.Encode failed ", err)
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
 
 			return
 		}
@@ -50,7 +58,7 @@ func (h *RemoveProductsFromCartHandler) ServeHTTP(w http.ResponseWriter, r *http
 
 	if sku < 1 {
 		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "sku must be more than zero"); err != nil {
-			fmt.Println("json.Encode failed ", err)
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
 
 			return
 		}
@@ -62,7 +70,7 @@ func (h *RemoveProductsFromCartHandler) ServeHTTP(w http.ResponseWriter, r *http
 	userId, err := uuid.Parse(userIdRaw)
 	if err != nil {
 		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "user_id must be valid uuid"); err != nil {
-			fmt.Println("json.Encode failed ", err)
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
 
 			return
 		}
@@ -72,15 +80,17 @@ func (h *RemoveProductsFromCartHandler) ServeHTTP(w http.ResponseWriter, r *http
 
 	err = h.cartService.RemoveProduct(r.Context(), userId, uint64(sku))
 	if err != nil {
-		if err = httpPkg.NewErrorResponse(w, http.StatusInternalServerError, err.Error()); err != nil {
+		if err = httpPkg.WriteError(w, err); err != nil {
 			return
 		}
 
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	metrics.CartItemsRemovedTotal.Inc()
+
 	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 
 	return
 }
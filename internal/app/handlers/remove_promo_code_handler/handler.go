@@ -0,0 +1,69 @@
+package remove_promo_code_handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+	httpPkg "github.com/jva44ka/ozon-simulator-go-cart/pkg/http"
+)
+
+type CartService interface {
+	RemovePromoCode(ctx context.Context, userId uuid.UUID) error
+}
+
+type RemovePromoCodeHandler struct {
+	cartService CartService
+}
+
+func NewRemovePromoCodeHandler(cartService CartService) *RemovePromoCodeHandler {
+	return &RemovePromoCodeHandler{cartService: cartService}
+}
+
+// @Summary      Снять промокод с корзины
+// @Description  Метод снимает промокод с корзины пользователя.
+// Если промокод не применен, как и при успешном снятии, возвращается 200.
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id  path  string  true  "Токен пользователя"
+// @Param        If-Match  header  string  false  "ETag версии корзины, к которой применяется изменение"
+// @Param        Idempotency-Key  header  string  false  "Ключ идемпотентности: повтор с тем же ключом вернет сохраненный ответ"
+// @Success      200  {object}  RemovePromoCodeResponse
+// @Failure      400  {object}  httpPkg.ErrorResponse
+// @Failure      401  {object}  httpPkg.ErrorResponse
+// @Failure      403  {object}  httpPkg.ErrorResponse
+// @Failure      422  {object}  httpPkg.ErrorResponse
+// @Failure      412  {object}  httpPkg.ErrorResponse
+// @Router       /user/{user_id}/cart/promo [delete]
+func (h *RemovePromoCodeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	userIdRaw := r.PathValue("user_id")
+	userId, err := uuid.Parse(userIdRaw)
+	if err != nil {
+		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "user_id must be valid uuid"); err != nil {
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
+
+			return
+		}
+
+		return
+	}
+
+	err = h.cartService.RemovePromoCode(r.Context(), userId)
+	if err != nil {
+		if err = httpPkg.WriteError(w, err); err != nil {
+			return
+		}
+
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	return
+}
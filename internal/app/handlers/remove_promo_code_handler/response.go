@@ -0,0 +1,3 @@
+package remove_promo_code_handler
+
+type RemovePromoCodeResponse struct{}
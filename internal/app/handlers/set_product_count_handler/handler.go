@@ -0,0 +1,120 @@
+package set_product_count_handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+	httpPkg "github.com/jva44ka/ozon-simulator-go-cart/pkg/http"
+)
+
+type CartService interface {
+	SetProductCount(ctx context.Context, userId uuid.UUID, sku uint64, count uint32) error
+}
+
+type SetProductCountHandler struct {
+	cartService CartService
+}
+
+func NewSetProductCountHandler(cartService CartService) *SetProductCountHandler {
+	return &SetProductCountHandler{cartService: cartService}
+}
+
+// @Summary      Установить количество товара в корзине
+// @Description  Метод устанавливает абсолютное количество экземпляров товара в корзине пользователя.
+// Если товара в корзине еще нет, проверяем, что он существует в специальном сервисе, и добавляем его.
+// Количество 0 удаляет товар из корзины.
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id  path  string  true  "Токен пользователя"
+// @Param        If-Match  header  string  false  "ETag версии корзины, к которой применяется изменение"
+// @Param        sku_id   path  uint64  true  "SKU товара"
+// @Param        body     body  SetProductCountRequest  true  "Тело запроса с количеством товаров"
+// @Param        Idempotency-Key  header  string  false  "Ключ идемпотентности: повтор с тем же ключом вернет сохраненный ответ"
+// @Success      200  {object}  SetProductCountResponse
+// @Failure      400  {object}  httpPkg.ErrorResponse
+// @Failure      404  {object}  httpPkg.ErrorResponse
+// @Failure      503  {object}  httpPkg.ErrorResponse
+// @Failure      401  {object}  httpPkg.ErrorResponse
+// @Failure      403  {object}  httpPkg.ErrorResponse
+// @Failure      422  {object}  httpPkg.ErrorResponse
+// @Failure      412  {object}  httpPkg.ErrorResponse
+// @Router       /user/{user_id}/cart/{sku_id} [put]
+func (h *SetProductCountHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	skuRaw := r.PathValue("sku_id")
+	sku, err := strconv.Atoi(skuRaw)
+	if err != nil {
+		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "sku must be more than zero"); err != nil {
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
+
+			return
+		}
+
+		return
+	}
+
+	if sku < 1 {
+		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "sku must be more than zero"); err != nil {
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
+
+			return
+		}
+
+		return
+	}
+
+	userIdRaw := r.PathValue("user_id")
+	userId, err := uuid.Parse(userIdRaw)
+	if err != nil {
+		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "user_id must be valid uuid"); err != nil {
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
+
+			return
+		}
+
+		return
+	}
+
+	var request SetProductCountRequest
+
+	if err = json.NewDecoder(r.Body).Decode(&request); err != nil {
+		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, err.Error()); err != nil {
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
+
+			return
+		}
+
+		return
+	}
+
+	if request.Count == nil {
+		if err = httpPkg.NewErrorResponse(w, http.StatusBadRequest, "count is required"); err != nil {
+			logger.FromContext(r.Context()).Error("json.Encode failed", "error", err)
+
+			return
+		}
+
+		return
+	}
+
+	err = h.cartService.SetProductCount(r.Context(), userId, uint64(sku), *request.Count)
+	if err != nil {
+		if err = httpPkg.WriteError(w, err); err != nil {
+			return
+		}
+
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	return
+}
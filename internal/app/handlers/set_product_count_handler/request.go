@@ -0,0 +1,6 @@
+package set_product_count_handler
+
+type SetProductCountRequest struct {
+	// Count обязателен: без него тело декодировалось бы в 0 и удаляло товар
+	Count *uint32 `json:"count"`
+}
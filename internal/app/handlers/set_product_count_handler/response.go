@@ -0,0 +1,3 @@
+package set_product_count_handler
+
+type SetProductCountResponse struct{}
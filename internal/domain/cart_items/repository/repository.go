@@ -2,57 +2,254 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
 )
 
+type cartItemKey struct {
+	userId uuid.UUID
+	sku    uint64
+}
+
 type InMemoryCartItemRepository struct {
-	storage []model.CartItem
-	mutex   sync.RWMutex
+	storage  map[uuid.UUID]map[uint64]model.CartItem
+	index    map[uint64]cartItemKey
+	versions map[uuid.UUID]uint64
+	promos   map[uuid.UUID]string
+	mutex    sync.RWMutex
 
 	idFactory atomic.Uint64
+
+	now func() time.Time
 }
 
-func NewCartItemRepository(cap int) *InMemoryCartItemRepository {
+func NewInMemoryCartItemRepository() *InMemoryCartItemRepository {
 	return &InMemoryCartItemRepository{
-		storage: make([]model.CartItem, cap),
+		storage:  make(map[uuid.UUID]map[uint64]model.CartItem),
+		index:    make(map[uint64]cartItemKey),
+		versions: make(map[uuid.UUID]uint64),
+		promos:   make(map[uuid.UUID]string),
+		now:      time.Now,
 	}
 }
 
-func (r *InMemoryCartItemRepository) AddCartItem(_ context.Context, cartItem model.CartItem) error {
-	cartItemId := r.idFactory.Add(1)
-	cartItem.Id = cartItemId
+func (r *InMemoryCartItemRepository) AddCartItem(_ context.Context, cartItem model.CartItem) (*model.CartItem, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.storage[cartItem.UserId][cartItem.SkuId]; ok {
+		return nil, fmt.Errorf("failed to insert cart item: %w", model.ErrCartItemAlreadyExists)
+	}
+
+	result := r.insert(cartItem)
+
+	return &result, nil
+}
 
+func (r *InMemoryCartItemRepository) UpsertCartItem(_ context.Context, cartItem model.CartItem) (*model.CartItem, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	for _, storageItem := range r.storage {
-		if storageItem.SkuId == cartItem.SkuId && storageItem.UserId == cartItem.UserId {
-			storageItem.Count += cartItem.Count
+	storageItem, ok := r.storage[cartItem.UserId][cartItem.SkuId]
+	if !ok {
+		result := r.insert(cartItem)
 
-			return nil
-		}
+		return &result, nil
 	}
 
-	r.storage = append(r.storage, cartItem)
+	storageItem.Count += cartItem.Count
+	storageItem.UpdatedAt = r.now()
+	r.storage[cartItem.UserId][cartItem.SkuId] = storageItem
+	r.versions[cartItem.UserId]++
 
-	return nil
+	return &storageItem, nil
+}
+
+func (r *InMemoryCartItemRepository) UpdateCartItem(_ context.Context, id uint64, cartItem model.CartItem) (*model.CartItem, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key, ok := r.index[id]
+	if !ok {
+		return nil, model.ErrCartItemsNotFound
+	}
+
+	storageItem := r.storage[key.userId][key.sku]
+	storageItem.Count = cartItem.Count
+	storageItem.UpdatedAt = r.now()
+	r.storage[key.userId][key.sku] = storageItem
+	r.versions[key.userId]++
+
+	return &storageItem, nil
 }
 
 func (r *InMemoryCartItemRepository) GetCartItemsByUserId(_ context.Context, userId uuid.UUID) ([]model.CartItem, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	result := make([]model.CartItem, 0, len(r.storage))
+	userItems := r.storage[userId]
+	result := make([]model.CartItem, 0, len(userItems))
 
-	for _, storageItem := range r.storage {
-		if storageItem.UserId == userId {
-			result = append(result, storageItem)
-		}
+	for _, storageItem := range userItems {
+		result = append(result, storageItem)
 	}
 
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].SkuId < result[j].SkuId
+	})
+
 	return result, nil
 }
+
+func (r *InMemoryCartItemRepository) GetCartItem(_ context.Context, userId uuid.UUID, sku uint64) (*model.CartItem, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	storageItem, ok := r.storage[userId][sku]
+	if !ok {
+		return nil, model.ErrCartItemsNotFound
+	}
+
+	return &storageItem, nil
+}
+
+func (r *InMemoryCartItemRepository) RemoveCartItem(_ context.Context, userId uuid.UUID, sku uint64) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	storageItem, ok := r.storage[userId][sku]
+	if !ok {
+		return false, nil
+	}
+
+	delete(r.index, storageItem.Id)
+	delete(r.storage[userId], sku)
+	r.versions[userId]++
+
+	if len(r.storage[userId]) == 0 {
+		delete(r.storage, userId)
+	}
+
+	return true, nil
+}
+
+func (r *InMemoryCartItemRepository) RemoveAllCartItemsByUserId(_ context.Context, userId uuid.UUID) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.storage[userId]) == 0 {
+		return false, nil
+	}
+
+	for _, storageItem := range r.storage[userId] {
+		delete(r.index, storageItem.Id)
+	}
+
+	delete(r.storage, userId)
+	r.versions[userId]++
+
+	return true, nil
+}
+
+// RemoveExpiredCarts удаляет не более limit корзин, которые не менялись с idleBefore, начиная с самых старых,
+// и возвращает их владельцев. Вместе с позициями снимается промокод.
+func (r *InMemoryCartItemRepository) RemoveExpiredCarts(_ context.Context, idleBefore time.Time, limit int) ([]uuid.UUID, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	type expiredCart struct {
+		userId    uuid.UUID
+		updatedAt time.Time
+	}
+
+	expired := make([]expiredCart, 0)
+	for userId, userItems := range r.storage {
+		var updatedAt time.Time
+		for _, storageItem := range userItems {
+			if storageItem.UpdatedAt.After(updatedAt) {
+				updatedAt = storageItem.UpdatedAt
+			}
+		}
+
+		if updatedAt.Before(idleBefore) {
+			expired = append(expired, expiredCart{userId: userId, updatedAt: updatedAt})
+		}
+	}
+
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].updatedAt.Before(expired[j].updatedAt)
+	})
+
+	userIds := make([]uuid.UUID, 0, min(limit, len(expired)))
+	for _, cart := range expired[:min(limit, len(expired))] {
+		for _, storageItem := range r.storage[cart.userId] {
+			delete(r.index, storageItem.Id)
+		}
+
+		delete(r.storage, cart.userId)
+		delete(r.promos, cart.userId)
+		r.versions[cart.userId]++
+
+		userIds = append(userIds, cart.userId)
+	}
+
+	return userIds, nil
+}
+
+func (r *InMemoryCartItemRepository) GetCartVersion(_ context.Context, userId uuid.UUID) (uint64, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.versions[userId], nil
+}
+
+// LockCartVersion в памяти ничего не блокирует: изменения и так сериализуются мьютексом
+func (r *InMemoryCartItemRepository) LockCartVersion(ctx context.Context, userId uuid.UUID) (uint64, error) {
+	return r.GetCartVersion(ctx, userId)
+}
+
+func (r *InMemoryCartItemRepository) SetCartPromoCode(_ context.Context, userId uuid.UUID, code string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if code == "" {
+		delete(r.promos, userId)
+	} else {
+		r.promos[userId] = code
+	}
+	r.versions[userId]++
+
+	return nil
+}
+
+func (r *InMemoryCartItemRepository) GetCartPromoCode(_ context.Context, userId uuid.UUID) (string, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.promos[userId], nil
+}
+
+// insert должен вызываться под r.mutex.
+func (r *InMemoryCartItemRepository) insert(cartItem model.CartItem) model.CartItem {
+	cartItem.Id = r.idFactory.Add(1)
+	cartItem.CreatedAt = r.now()
+	cartItem.UpdatedAt = cartItem.CreatedAt
+
+	userItems, ok := r.storage[cartItem.UserId]
+	if !ok {
+		userItems = make(map[uint64]model.CartItem)
+		r.storage[cartItem.UserId] = userItems
+	}
+
+	userItems[cartItem.SkuId] = cartItem
+	r.index[cartItem.Id] = cartItemKey{userId: cartItem.UserId, sku: cartItem.SkuId}
+	r.versions[cartItem.UserId]++
+
+	return cartItem
+}
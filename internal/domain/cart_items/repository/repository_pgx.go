@@ -4,13 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/database"
 )
 
+const uniqueViolationCode = "23505"
+
 type PgxCartItemRepository struct {
 	pool *pgxpool.Pool
 }
@@ -20,25 +25,26 @@ func NewPgxCartItemRepository(pool *pgxpool.Pool) *PgxCartItemRepository {
 }
 
 type CartItemRow struct {
-	Id     uint64
-	SkuId  uint64
-	UserId uuid.UUID
-	Count  uint32
+	Id        uint64
+	SkuId     uint64
+	UserId    uuid.UUID
+	Count     uint32
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 func (r *PgxCartItemRepository) GetCartItemsByUserId(ctx context.Context, userId uuid.UUID) ([]model.CartItem, error) {
 	const query = `
-SELECT id, sku_id, user_id, count 
+SELECT id, sku_id, user_id, count, created_at, updated_at 
 FROM cart_items 
 WHERE user_id = $1
-ORDER BY id DESC`
+ORDER BY sku_id`
 
-	rows, err := r.pool.Query(ctx, query, userId)
+	rows, err := database.QuerierFromContext(ctx, r.pool).Query(ctx, query, userId)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, model.ErrCartItemsNotFound
-		}
+		return nil, fmt.Errorf("CartItemRepository.GetCartItemsByUserId: %w", err)
 	}
+	defer rows.Close()
 
 	var cartItemRows []CartItemRow
 	for rows.Next() {
@@ -47,7 +53,9 @@ ORDER BY id DESC`
 			&cartItemRow.Id,
 			&cartItemRow.SkuId,
 			&cartItemRow.UserId,
-			&cartItemRow.Count)
+			&cartItemRow.Count,
+			&cartItemRow.CreatedAt,
+			&cartItemRow.UpdatedAt)
 
 		if err != nil {
 			return nil, fmt.Errorf("CartItemRepository.GetCartItemsByUserId: %w", err)
@@ -56,37 +64,49 @@ ORDER BY id DESC`
 		cartItemRows = append(cartItemRows, cartItemRow)
 	}
 
-	var result []model.CartItem
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("CartItemRepository.GetCartItemsByUserId: %w", err)
+	}
+
+	result := make([]model.CartItem, 0, len(cartItemRows))
 
 	for _, cartItemRow := range cartItemRows {
 		result = append(result, model.CartItem{
-			Id:     cartItemRow.Id,
-			SkuId:  cartItemRow.SkuId,
-			UserId: cartItemRow.UserId,
-			Count:  cartItemRow.Count,
+			Id:        cartItemRow.Id,
+			SkuId:     cartItemRow.SkuId,
+			UserId:    cartItemRow.UserId,
+			Count:     cartItemRow.Count,
+			CreatedAt: cartItemRow.CreatedAt,
+			UpdatedAt: cartItemRow.UpdatedAt,
 		})
 	}
 
-	defer rows.Close()
-
 	return result, nil
 }
 
 func (r *PgxCartItemRepository) GetCartItem(ctx context.Context, userId uuid.UUID, sku uint64) (*model.CartItem, error) {
 	const query = `
 SELECT 
-    id, sku_id, user_id, count 
+    id, sku_id, user_id, count, created_at, updated_at 
 FROM 
     cart_items 
 WHERE 
     user_id = $1
-    AND sku_id = $2`
+    AND sku_id = $2
+FOR UPDATE`
 
-	row := r.pool.QueryRow(ctx, query, userId, sku)
+	// Внутри транзакции строка блокируется до ее завершения, что исключает потерю обновлений
+	row := database.QuerierFromContext(ctx, r.pool).QueryRow(ctx, query, userId, sku)
 
 	var productRow = CartItemRow{}
 
-	err := row.Scan(&productRow.Id, &productRow.SkuId, &productRow.UserId, &productRow.Count)
+	err := row.Scan(
+		&productRow.Id,
+		&productRow.SkuId,
+		&productRow.UserId,
+		&productRow.Count,
+		&productRow.CreatedAt,
+		&productRow.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, model.ErrCartItemsNotFound
@@ -96,10 +116,12 @@ WHERE
 
 	// Преобразуем типы в модель приложения
 	result := &model.CartItem{
-		Id:     productRow.Id,
-		SkuId:  productRow.SkuId,
-		UserId: productRow.UserId,
-		Count:  productRow.Count,
+		Id:        productRow.Id,
+		SkuId:     productRow.SkuId,
+		UserId:    productRow.UserId,
+		Count:     productRow.Count,
+		CreatedAt: productRow.CreatedAt,
+		UpdatedAt: productRow.UpdatedAt,
 	}
 
 	return result, nil
@@ -112,21 +134,77 @@ INSERT INTO
 VALUES 
     ($1, $2, $3)
 RETURNING 
-	id;`
-
-	var id int64
-	err := pgx.BeginTxFunc(ctx, r.pool, pgx.TxOptions{}, func(tx pgx.Tx) error {
-		return tx.QueryRow(ctx, query, cartItem.SkuId, cartItem.UserId, cartItem.Count).Scan(&id)
-	})
+	id, created_at, updated_at;`
+
+	var (
+		id                   int64
+		createdAt, updatedAt time.Time
+	)
+	err := database.QuerierFromContext(ctx, r.pool).
+		QueryRow(ctx, query, cartItem.SkuId, cartItem.UserId, cartItem.Count).
+		Scan(&id, &createdAt, &updatedAt)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return nil, fmt.Errorf("failed to insert cart item: %w", model.ErrCartItemAlreadyExists)
+		}
+
 		return nil, fmt.Errorf("failed to insert cart item: %w", err)
 	}
 
+	if err = r.bumpCartVersion(ctx, cartItem.UserId); err != nil {
+		return nil, err
+	}
+
 	result := model.CartItem{
-		Id:     uint64(id),
-		SkuId:  cartItem.SkuId,
-		UserId: cartItem.UserId,
-		Count:  cartItem.Count,
+		Id:        uint64(id),
+		SkuId:     cartItem.SkuId,
+		UserId:    cartItem.UserId,
+		Count:     cartItem.Count,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+	}
+
+	return &result, nil
+}
+
+// UpsertCartItem добавляет позицию в корзину или атомарно увеличивает количество уже существующей.
+func (r *PgxCartItemRepository) UpsertCartItem(ctx context.Context, cartItem model.CartItem) (*model.CartItem, error) {
+	const query = `
+INSERT INTO 
+    cart_items (sku_id, user_id, count) 
+VALUES 
+    ($1, $2, $3)
+ON CONFLICT (user_id, sku_id) DO UPDATE
+SET 
+    count = cart_items.count + EXCLUDED.count,
+    updated_at = now()
+RETURNING 
+	id, count, created_at, updated_at;`
+
+	var (
+		id                   int64
+		count                uint32
+		createdAt, updatedAt time.Time
+	)
+	err := database.QuerierFromContext(ctx, r.pool).
+		QueryRow(ctx, query, cartItem.SkuId, cartItem.UserId, cartItem.Count).
+		Scan(&id, &count, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert cart item: %w", err)
+	}
+
+	if err = r.bumpCartVersion(ctx, cartItem.UserId); err != nil {
+		return nil, err
+	}
+
+	result := model.CartItem{
+		Id:        uint64(id),
+		SkuId:     cartItem.SkuId,
+		UserId:    cartItem.UserId,
+		Count:     count,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
 	}
 
 	return &result, nil
@@ -137,29 +215,47 @@ func (r *PgxCartItemRepository) UpdateCartItem(ctx context.Context, id uint64, c
 UPDATE 
     cart_items
 SET
-	count = $2
+	count = $2,
+	updated_at = now()
 WHERE 
-    id = $1`
-
-	err := pgx.BeginTxFunc(ctx, r.pool, pgx.TxOptions{}, func(tx pgx.Tx) error {
-		_, err := tx.Exec(ctx, query, int64(id), cartItem.Count)
-		return err
-	})
+    id = $1
+RETURNING
+	id, sku_id, user_id, count, created_at, updated_at`
+
+	var cartItemRow CartItemRow
+	err := database.QuerierFromContext(ctx, r.pool).
+		QueryRow(ctx, query, int64(id), cartItem.Count).
+		Scan(
+			&cartItemRow.Id,
+			&cartItemRow.SkuId,
+			&cartItemRow.UserId,
+			&cartItemRow.Count,
+			&cartItemRow.CreatedAt,
+			&cartItemRow.UpdatedAt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to insert cart item: %w", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.ErrCartItemsNotFound
+		}
+		return nil, fmt.Errorf("failed to update cart item: %w", err)
+	}
+
+	if err = r.bumpCartVersion(ctx, cartItemRow.UserId); err != nil {
+		return nil, err
 	}
 
 	result := model.CartItem{
-		Id:     id,
-		SkuId:  cartItem.SkuId,
-		UserId: cartItem.UserId,
-		Count:  cartItem.Count,
+		Id:        cartItemRow.Id,
+		SkuId:     cartItemRow.SkuId,
+		UserId:    cartItemRow.UserId,
+		Count:     cartItemRow.Count,
+		CreatedAt: cartItemRow.CreatedAt,
+		UpdatedAt: cartItemRow.UpdatedAt,
 	}
 
 	return &result, nil
 }
 
-func (r *PgxCartItemRepository) RemoveCartItem(ctx context.Context, userId uuid.UUID, sku uint64) error {
+func (r *PgxCartItemRepository) RemoveCartItem(ctx context.Context, userId uuid.UUID, sku uint64) (bool, error) {
 	const query = `
 DELETE FROM
     cart_items
@@ -167,30 +263,198 @@ WHERE
     user_id = $1
 	AND sku_id = $2;`
 
-	err := pgx.BeginTxFunc(ctx, r.pool, pgx.TxOptions{}, func(tx pgx.Tx) error {
-		_, err := tx.Exec(ctx, query, userId, sku)
-		return err
-	})
+	tag, err := database.QuerierFromContext(ctx, r.pool).Exec(ctx, query, userId, sku)
 	if err != nil {
-		return fmt.Errorf("failed to delete cart item: %w", err)
+		return false, fmt.Errorf("failed to delete cart item: %w", err)
 	}
 
-	return nil
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if err = r.bumpCartVersion(ctx, userId); err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
 
-func (r *PgxCartItemRepository) RemoveAllCartItemsByUserId(ctx context.Context, userId uuid.UUID) error {
+func (r *PgxCartItemRepository) RemoveAllCartItemsByUserId(ctx context.Context, userId uuid.UUID) (bool, error) {
 	const query = `
 DELETE FROM
     cart_items
 WHERE 
     user_id = $1;`
 
-	err := pgx.BeginTxFunc(ctx, r.pool, pgx.TxOptions{}, func(tx pgx.Tx) error {
-		_, err := tx.Exec(ctx, query, userId)
-		return err
-	})
+	tag, err := database.QuerierFromContext(ctx, r.pool).Exec(ctx, query, userId)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete all cart items by user id: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if err = r.bumpCartVersion(ctx, userId); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *PgxCartItemRepository) GetCartVersion(ctx context.Context, userId uuid.UUID) (uint64, error) {
+	const query = `
+SELECT version 
+FROM carts 
+WHERE user_id = $1`
+
+	var version int64
+	err := database.QuerierFromContext(ctx, r.pool).QueryRow(ctx, query, userId).Scan(&version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("PgxCartItemRepository.GetCartVersion: %w", err)
+	}
+
+	return uint64(version), nil
+}
+
+// LockCartVersion возвращает версию корзины и блокирует ее до конца транзакции.
+// Строка создается заранее, чтобы блокировка работала и для еще не созданной корзины.
+func (r *PgxCartItemRepository) LockCartVersion(ctx context.Context, userId uuid.UUID) (uint64, error) {
+	const insertQuery = `
+INSERT INTO 
+    carts (user_id) 
+VALUES 
+    ($1)
+ON CONFLICT (user_id) DO NOTHING`
+
+	const selectQuery = `
+SELECT version 
+FROM carts 
+WHERE user_id = $1
+FOR UPDATE`
+
+	querier := database.QuerierFromContext(ctx, r.pool)
+
+	if _, err := querier.Exec(ctx, insertQuery, userId); err != nil {
+		return 0, fmt.Errorf("PgxCartItemRepository.LockCartVersion: %w", err)
+	}
+
+	var version int64
+	if err := querier.QueryRow(ctx, selectQuery, userId).Scan(&version); err != nil {
+		return 0, fmt.Errorf("PgxCartItemRepository.LockCartVersion: %w", err)
+	}
+
+	return uint64(version), nil
+}
+
+// SetCartPromoCode сохраняет промокод корзины; пустой code снимает его. Версия корзины увеличивается,
+// так как от промокода зависит итоговая сумма.
+func (r *PgxCartItemRepository) SetCartPromoCode(ctx context.Context, userId uuid.UUID, code string) error {
+	const query = `
+INSERT INTO 
+    carts (user_id, version, promo_code) 
+VALUES 
+    ($1, 1, NULLIF($2, ''))
+ON CONFLICT (user_id) DO UPDATE
+SET 
+    version = carts.version + 1,
+    promo_code = EXCLUDED.promo_code,
+    updated_at = now()`
+
+	_, err := database.QuerierFromContext(ctx, r.pool).Exec(ctx, query, userId, code)
+	if err != nil {
+		return fmt.Errorf("PgxCartItemRepository.SetCartPromoCode: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PgxCartItemRepository) GetCartPromoCode(ctx context.Context, userId uuid.UUID) (string, error) {
+	const query = `
+SELECT coalesce(promo_code, '') 
+FROM carts 
+WHERE user_id = $1`
+
+	var code string
+	err := database.QuerierFromContext(ctx, r.pool).QueryRow(ctx, query, userId).Scan(&code)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("PgxCartItemRepository.GetCartPromoCode: %w", err)
+	}
+
+	return code, nil
+}
+
+// RemoveExpiredCarts удаляет не более limit корзин, которые не менялись с idleBefore, начиная с самых старых,
+// и возвращает их владельцев. Строки carts блокируются с SKIP LOCKED, поэтому корзины, которые сейчас меняются,
+// пропускаются, а для заблокированных строк условие простоя перепроверяется на их последней версии.
+// Вместе с позициями снимается промокод и увеличивается версия корзины.
+func (r *PgxCartItemRepository) RemoveExpiredCarts(ctx context.Context, idleBefore time.Time, limit int) ([]uuid.UUID, error) {
+	const query = `
+WITH expired AS (
+    SELECT carts.user_id
+    FROM carts
+    WHERE carts.updated_at < $1
+      AND EXISTS (SELECT 1 FROM cart_items WHERE cart_items.user_id = carts.user_id)
+      AND NOT EXISTS (SELECT 1 FROM cart_items WHERE cart_items.user_id = carts.user_id AND cart_items.updated_at >= $1)
+    ORDER BY carts.updated_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+), cleared AS (
+    UPDATE 
+        carts
+    SET 
+        version = carts.version + 1,
+        promo_code = NULL,
+        updated_at = now()
+    FROM 
+        expired
+    WHERE 
+        carts.user_id = expired.user_id
+    RETURNING 
+        carts.user_id
+), deleted AS (
+    DELETE FROM
+        cart_items
+    WHERE 
+        user_id IN (SELECT user_id FROM cleared)
+)
+SELECT user_id FROM cleared`
+
+	rows, err := database.QuerierFromContext(ctx, r.pool).Query(ctx, query, idleBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("PgxCartItemRepository.RemoveExpiredCarts: %w", err)
+	}
+
+	userIds, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("PgxCartItemRepository.RemoveExpiredCarts: %w", err)
+	}
+
+	return userIds, nil
+}
+
+// bumpCartVersion увеличивает версию корзины. Вызывается после каждого изменения позиций,
+// поэтому изменение и новая версия должны выполняться в одной транзакции.
+func (r *PgxCartItemRepository) bumpCartVersion(ctx context.Context, userId uuid.UUID) error {
+	const query = `
+INSERT INTO 
+    carts (user_id, version) 
+VALUES 
+    ($1, 1)
+ON CONFLICT (user_id) DO UPDATE
+SET 
+    version = carts.version + 1,
+    updated_at = now()`
+
+	_, err := database.QuerierFromContext(ctx, r.pool).Exec(ctx, query, userId)
 	if err != nil {
-		return fmt.Errorf("failed to delete all cart items by user id: %w", err)
+		return fmt.Errorf("failed to bump cart version: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/cart_items/repository/repositorytest"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/cart_items/service"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+// Интеграционные тесты запускаются только против локального Postgres с примененными миграциями
+const testDatabaseDsnEnv = "CART_TEST_DATABASE_DSN"
+
+func newTestPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	dsn := os.Getenv(testDatabaseDsnEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseDsnEnv)
+	}
+
+	pool, err := pgxpool.New(context.Background(), dsn)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+func TestPgxCartItemRepository_UpsertCartItem_Concurrent(t *testing.T) {
+	repo := NewPgxCartItemRepository(newTestPool(t))
+	ctx := context.Background()
+	userId := uuid.New()
+
+	t.Cleanup(func() {
+		_, _ = repo.RemoveAllCartItemsByUserId(ctx, userId)
+	})
+
+	const (
+		workers = 50
+		count   = 3
+	)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := repo.UpsertCartItem(ctx, model.CartItem{UserId: userId, SkuId: 1, Count: count})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	items, err := repo.GetCartItemsByUserId(ctx, userId)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, uint32(workers*count), items[0].Count)
+}
+
+func TestPgxCartItemRepository_Conformance(t *testing.T) {
+	pool := newTestPool(t)
+
+	repositorytest.Run(t, func(t *testing.T) service.CartRepository {
+		return NewPgxCartItemRepository(pool)
+	})
+}
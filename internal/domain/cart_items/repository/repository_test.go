@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/cart_items/repository/repositorytest"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/cart_items/service"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+var _ service.CartRepository = (*InMemoryCartItemRepository)(nil)
+
+func TestInMemoryCartItemRepository_NewIsEmpty(t *testing.T) {
+	repo := NewInMemoryCartItemRepository()
+
+	items, err := repo.GetCartItemsByUserId(context.Background(), uuid.Nil)
+	require.NoError(t, err)
+	require.Empty(t, items)
+}
+
+func TestInMemoryCartItemRepository_UpsertCartItem_Accumulates(t *testing.T) {
+	repo := NewInMemoryCartItemRepository()
+	ctx := context.Background()
+	userId := uuid.New()
+
+	first, err := repo.UpsertCartItem(ctx, model.CartItem{UserId: userId, SkuId: 1, Count: 2})
+	require.NoError(t, err)
+
+	second, err := repo.UpsertCartItem(ctx, model.CartItem{UserId: userId, SkuId: 1, Count: 3})
+	require.NoError(t, err)
+	require.Equal(t, first.Id, second.Id)
+	require.Equal(t, uint32(5), second.Count)
+
+	stored, err := repo.GetCartItem(ctx, userId, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint32(5), stored.Count)
+}
+
+func TestInMemoryCartItemRepository_UpsertCartItem_Concurrent(t *testing.T) {
+	repo := NewInMemoryCartItemRepository()
+	ctx := context.Background()
+	userId := uuid.New()
+
+	const workers = 50
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := repo.UpsertCartItem(ctx, model.CartItem{UserId: userId, SkuId: 1, Count: 1})
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	items, err := repo.GetCartItemsByUserId(ctx, userId)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, uint32(workers), items[0].Count)
+}
+
+func TestInMemoryCartItemRepository_Conformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) service.CartRepository {
+		return NewInMemoryCartItemRepository()
+	})
+}
+
+func TestInMemoryCartItemRepository_Timestamps(t *testing.T) {
+	repo := NewInMemoryCartItemRepository()
+	ctx := context.Background()
+	userId := uuid.New()
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+
+	added, err := repo.AddCartItem(ctx, model.CartItem{UserId: userId, SkuId: 1, Count: 1})
+	require.NoError(t, err)
+	require.Equal(t, now, added.CreatedAt)
+	require.Equal(t, now, added.UpdatedAt)
+
+	now = now.Add(time.Hour)
+	upserted, err := repo.UpsertCartItem(ctx, model.CartItem{UserId: userId, SkuId: 1, Count: 1})
+	require.NoError(t, err)
+	require.Equal(t, added.CreatedAt, upserted.CreatedAt)
+	require.Equal(t, now, upserted.UpdatedAt)
+}
+
+func TestInMemoryCartItemRepository_RemoveExpiredCarts(t *testing.T) {
+	repo := NewInMemoryCartItemRepository()
+	ctx := context.Background()
+
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	repo.now = func() time.Time { return now }
+
+	oldest, older, fresh := uuid.New(), uuid.New(), uuid.New()
+	for i, userId := range []uuid.UUID{oldest, older, fresh} {
+		now = start.Add(time.Duration(i) * time.Hour)
+		_, err := repo.AddCartItem(ctx, model.CartItem{UserId: userId, SkuId: 1, Count: 1})
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.SetCartPromoCode(ctx, oldest, "SALE10"))
+
+	// Корзина считается активной по последнему изменению любой позиции
+	now = start.Add(3 * time.Hour)
+	_, err := repo.AddCartItem(ctx, model.CartItem{UserId: older, SkuId: 2, Count: 1})
+	require.NoError(t, err)
+
+	expired, err := repo.RemoveExpiredCarts(ctx, start.Add(90*time.Minute), 10)
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{oldest}, expired)
+
+	expired, err = repo.RemoveExpiredCarts(ctx, start.Add(4*time.Hour), 1)
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{fresh}, expired)
+
+	items, err := repo.GetCartItemsByUserId(ctx, older)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	version, err := repo.GetCartVersion(ctx, oldest)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), version)
+
+	code, err := repo.GetCartPromoCode(ctx, oldest)
+	require.NoError(t, err)
+	require.Empty(t, code)
+}
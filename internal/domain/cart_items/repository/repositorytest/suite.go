@@ -0,0 +1,386 @@
+// Package repositorytest содержит общий набор тестов для реализаций service.CartRepository.
+package repositorytest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/cart_items/service"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+type Factory func(t *testing.T) service.CartRepository
+
+// Run прогоняет поведенческие тесты против репозитория, созданного factory.
+// Тесты используют случайных пользователей, поэтому хранилище может быть общим.
+func Run(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo service.CartRepository)
+	}{
+		{"AddCartItem", testAddCartItem},
+		{"AddCartItemDuplicate", testAddCartItemDuplicate},
+		{"GetCartItemNotFound", testGetCartItemNotFound},
+		{"GetCartItemsByUserIdEmpty", testGetCartItemsByUserIdEmpty},
+		{"GetCartItemsByUserIdOrderedBySku", testGetCartItemsByUserIdOrderedBySku},
+		{"UpsertCartItem", testUpsertCartItem},
+		{"UpdateCartItem", testUpdateCartItem},
+		{"UpdateCartItemNotFound", testUpdateCartItemNotFound},
+		{"RemoveCartItem", testRemoveCartItem},
+		{"RemoveCartItemNotFound", testRemoveCartItemNotFound},
+		{"RemoveAllCartItemsByUserId", testRemoveAllCartItemsByUserId},
+		{"ConcurrentUpserts", testConcurrentUpserts},
+		{"ConcurrentWritersDifferentSkus", testConcurrentWritersDifferentSkus},
+		{"CartVersion", testCartVersion},
+		{"CartPromoCode", testCartPromoCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, factory(t))
+		})
+	}
+}
+
+func newUserId(t *testing.T, repo service.CartRepository) uuid.UUID {
+	t.Helper()
+
+	userId := uuid.New()
+	t.Cleanup(func() {
+		_, _ = repo.RemoveAllCartItemsByUserId(context.Background(), userId)
+	})
+
+	return userId
+}
+
+func testAddCartItem(t *testing.T, repo service.CartRepository) {
+	ctx := context.Background()
+	userId := newUserId(t, repo)
+
+	added, err := repo.AddCartItem(ctx, model.CartItem{UserId: userId, SkuId: 10, Count: 2})
+	require.NoError(t, err)
+	require.NotZero(t, added.Id)
+	require.Equal(t, userId, added.UserId)
+	require.Equal(t, uint64(10), added.SkuId)
+	require.Equal(t, uint32(2), added.Count)
+
+	stored, err := repo.GetCartItem(ctx, userId, 10)
+	require.NoError(t, err)
+	require.Equal(t, *added, *stored)
+}
+
+func testAddCartItemDuplicate(t *testing.T, repo service.CartRepository) {
+	ctx := context.Background()
+	userId := newUserId(t, repo)
+
+	_, err := repo.AddCartItem(ctx, model.CartItem{UserId: userId, SkuId: 10, Count: 2})
+	require.NoError(t, err)
+
+	_, err = repo.AddCartItem(ctx, model.CartItem{UserId: userId, SkuId: 10, Count: 3})
+	require.ErrorIs(t, err, model.ErrCartItemAlreadyExists)
+
+	stored, err := repo.GetCartItem(ctx, userId, 10)
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), stored.Count)
+}
+
+func testGetCartItemNotFound(t *testing.T, repo service.CartRepository) {
+	ctx := context.Background()
+	userId := newUserId(t, repo)
+
+	_, err := repo.AddCartItem(ctx, model.CartItem{UserId: userId, SkuId: 10, Count: 1})
+	require.NoError(t, err)
+
+	item, err := repo.GetCartItem(ctx, userId, 11)
+	require.ErrorIs(t, err, model.ErrCartItemsNotFound)
+	require.Nil(t, item)
+
+	item, err = repo.GetCartItem(ctx, uuid.New(), 10)
+	require.ErrorIs(t, err, model.ErrCartItemsNotFound)
+	require.Nil(t, item)
+}
+
+func testGetCartItemsByUserIdEmpty(t *testing.T, repo service.CartRepository) {
+	items, err := repo.GetCartItemsByUserId(context.Background(), newUserId(t, repo))
+	require.NoError(t, err)
+	require.Empty(t, items)
+}
+
+func testGetCartItemsByUserIdOrderedBySku(t *testing.T, repo service.CartRepository) {
+	ctx := context.Background()
+	userId := newUserId(t, repo)
+	otherUserId := newUserId(t, repo)
+
+	for _, sku := range []uint64{30, 10, 20} {
+		_, err := repo.AddCartItem(ctx, model.CartItem{UserId: userId, SkuId: sku, Count: uint32(sku)})
+		require.NoError(t, err)
+	}
+
+	_, err := repo.AddCartItem(ctx, model.CartItem{UserId: otherUserId, SkuId: 15, Count: 1})
+	require.NoError(t, err)
+
+	items, err := repo.GetCartItemsByUserId(ctx, userId)
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+
+	for i, sku := range []uint64{10, 20, 30} {
+		require.Equal(t, sku, items[i].SkuId)
+		require.Equal(t, uint32(sku), items[i].Count)
+		require.Equal(t, userId, items[i].UserId)
+	}
+}
+
+func testUpsertCartItem(t *testing.T, repo service.CartRepository) {
+	ctx := context.Background()
+	userId := newUserId(t, repo)
+
+	inserted, err := repo.UpsertCartItem(ctx, model.CartItem{UserId: userId, SkuId: 10, Count: 2})
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), inserted.Count)
+
+	updated, err := repo.UpsertCartItem(ctx, model.CartItem{UserId: userId, SkuId: 10, Count: 3})
+	require.NoError(t, err)
+	require.Equal(t, inserted.Id, updated.Id)
+	require.Equal(t, uint32(5), updated.Count)
+
+	items, err := repo.GetCartItemsByUserId(ctx, userId)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, uint32(5), items[0].Count)
+}
+
+func testUpdateCartItem(t *testing.T, repo service.CartRepository) {
+	ctx := context.Background()
+	userId := newUserId(t, repo)
+
+	added, err := repo.AddCartItem(ctx, model.CartItem{UserId: userId, SkuId: 10, Count: 2})
+	require.NoError(t, err)
+
+	updated, err := repo.UpdateCartItem(ctx, added.Id, model.CartItem{Count: 7})
+	require.NoError(t, err)
+	require.Equal(t, added.Id, updated.Id)
+	require.Equal(t, userId, updated.UserId)
+	require.Equal(t, uint64(10), updated.SkuId)
+	require.Equal(t, uint32(7), updated.Count)
+
+	stored, err := repo.GetCartItem(ctx, userId, 10)
+	require.NoError(t, err)
+	require.Equal(t, uint32(7), stored.Count)
+}
+
+func testUpdateCartItemNotFound(t *testing.T, repo service.CartRepository) {
+	ctx := context.Background()
+	userId := newUserId(t, repo)
+
+	added, err := repo.AddCartItem(ctx, model.CartItem{UserId: userId, SkuId: 10, Count: 2})
+	require.NoError(t, err)
+	_, err = repo.RemoveCartItem(ctx, userId, 10)
+	require.NoError(t, err)
+
+	updated, err := repo.UpdateCartItem(ctx, added.Id, model.CartItem{Count: 7})
+	require.ErrorIs(t, err, model.ErrCartItemsNotFound)
+	require.Nil(t, updated)
+}
+
+func testRemoveCartItem(t *testing.T, repo service.CartRepository) {
+	ctx := context.Background()
+	userId := newUserId(t, repo)
+
+	for _, sku := range []uint64{10, 20} {
+		_, err := repo.AddCartItem(ctx, model.CartItem{UserId: userId, SkuId: sku, Count: 1})
+		require.NoError(t, err)
+	}
+
+	removed, err := repo.RemoveCartItem(ctx, userId, 10)
+	require.NoError(t, err)
+	require.True(t, removed)
+
+	_, err = repo.GetCartItem(ctx, userId, 10)
+	require.ErrorIs(t, err, model.ErrCartItemsNotFound)
+
+	items, err := repo.GetCartItemsByUserId(ctx, userId)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, uint64(20), items[0].SkuId)
+
+	// После удаления позицию можно добавить заново
+	_, err = repo.AddCartItem(ctx, model.CartItem{UserId: userId, SkuId: 10, Count: 4})
+	require.NoError(t, err)
+}
+
+func testRemoveCartItemNotFound(t *testing.T, repo service.CartRepository) {
+	removed, err := repo.RemoveCartItem(context.Background(), newUserId(t, repo), 10)
+	require.NoError(t, err)
+	require.False(t, removed)
+}
+
+func testRemoveAllCartItemsByUserId(t *testing.T, repo service.CartRepository) {
+	ctx := context.Background()
+	userId := newUserId(t, repo)
+	otherUserId := newUserId(t, repo)
+
+	for _, sku := range []uint64{10, 20} {
+		_, err := repo.AddCartItem(ctx, model.CartItem{UserId: userId, SkuId: sku, Count: 1})
+		require.NoError(t, err)
+	}
+
+	_, err := repo.AddCartItem(ctx, model.CartItem{UserId: otherUserId, SkuId: 10, Count: 1})
+	require.NoError(t, err)
+
+	removed, err := repo.RemoveAllCartItemsByUserId(ctx, userId)
+	require.NoError(t, err)
+	require.True(t, removed)
+
+	items, err := repo.GetCartItemsByUserId(ctx, userId)
+	require.NoError(t, err)
+	require.Empty(t, items)
+
+	items, err = repo.GetCartItemsByUserId(ctx, otherUserId)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	// Очистка пустой корзины не является ошибкой
+	removed, err = repo.RemoveAllCartItemsByUserId(ctx, userId)
+	require.NoError(t, err)
+	require.False(t, removed)
+}
+
+func testConcurrentUpserts(t *testing.T, repo service.CartRepository) {
+	ctx := context.Background()
+	userId := newUserId(t, repo)
+
+	const (
+		workers = 20
+		count   = 3
+	)
+
+	errs := runConcurrently(workers, func(int) error {
+		_, err := repo.UpsertCartItem(ctx, model.CartItem{UserId: userId, SkuId: 10, Count: count})
+		return err
+	})
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	items, err := repo.GetCartItemsByUserId(ctx, userId)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, uint32(workers*count), items[0].Count)
+}
+
+func testConcurrentWritersDifferentSkus(t *testing.T, repo service.CartRepository) {
+	ctx := context.Background()
+	userId := newUserId(t, repo)
+
+	const workers = 20
+
+	errs := runConcurrently(workers, func(i int) error {
+		_, err := repo.AddCartItem(ctx, model.CartItem{UserId: userId, SkuId: uint64(i + 1), Count: 1})
+		return err
+	})
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	items, err := repo.GetCartItemsByUserId(ctx, userId)
+	require.NoError(t, err)
+	require.Len(t, items, workers)
+
+	ids := make(map[uint64]struct{}, workers)
+	for _, item := range items {
+		ids[item.Id] = struct{}{}
+	}
+	require.Len(t, ids, workers)
+}
+
+func runConcurrently(workers int, fn func(i int) error) []error {
+	errs := make([]error, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(i)
+		}()
+	}
+	wg.Wait()
+
+	return errs
+}
+
+func testCartVersion(t *testing.T, repo service.CartRepository) {
+	ctx := context.Background()
+	userId := newUserId(t, repo)
+
+	requireVersion := func(expected uint64) {
+		t.Helper()
+
+		version, err := repo.GetCartVersion(ctx, userId)
+		require.NoError(t, err)
+		require.Equal(t, expected, version)
+	}
+
+	requireVersion(0)
+
+	added, err := repo.AddCartItem(ctx, model.CartItem{UserId: userId, SkuId: 1, Count: 1})
+	require.NoError(t, err)
+	requireVersion(1)
+
+	_, err = repo.UpsertCartItem(ctx, model.CartItem{UserId: userId, SkuId: 1, Count: 1})
+	require.NoError(t, err)
+	requireVersion(2)
+
+	_, err = repo.UpdateCartItem(ctx, added.Id, model.CartItem{Count: 5})
+	require.NoError(t, err)
+	requireVersion(3)
+
+	// Удаление отсутствующего товара корзину не меняет
+	_, err = repo.RemoveCartItem(ctx, userId, 2)
+	require.NoError(t, err)
+	requireVersion(3)
+
+	_, err = repo.RemoveCartItem(ctx, userId, 1)
+	require.NoError(t, err)
+	requireVersion(4)
+
+	_, err = repo.RemoveAllCartItemsByUserId(ctx, userId)
+	require.NoError(t, err)
+	requireVersion(4)
+
+	locked, err := repo.LockCartVersion(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), locked)
+}
+
+func testCartPromoCode(t *testing.T, repo service.CartRepository) {
+	ctx := context.Background()
+	userId := newUserId(t, repo)
+
+	code, err := repo.GetCartPromoCode(ctx, userId)
+	require.NoError(t, err)
+	require.Empty(t, code)
+
+	require.NoError(t, repo.SetCartPromoCode(ctx, userId, "WELCOME10"))
+
+	code, err = repo.GetCartPromoCode(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, "WELCOME10", code)
+
+	version, err := repo.GetCartVersion(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), version)
+
+	require.NoError(t, repo.SetCartPromoCode(ctx, userId, ""))
+
+	code, err = repo.GetCartPromoCode(ctx, userId)
+	require.NoError(t, err)
+	require.Empty(t, code)
+
+	version, err = repo.GetCartVersion(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), version)
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ApplyBatch применяет набор операций над корзиной в одной транзакции.
+// В режиме BatchModeAllOrNothing при ошибке любой операции не применяется ни одна из них
+// и возвращается model.ErrBatchNotApplied вместе с результатами по каждой операции.
+func (s *CartService) ApplyBatch(
+	ctx context.Context,
+	userId uuid.UUID,
+	operations []model.CartItemOperation,
+	mode model.BatchMode,
+) (_ []model.CartItemOperationResult, err error) {
+	ctx, span := tracer.Start(ctx, "CartService.ApplyBatch", trace.WithAttributes(
+		tracing.UserId(userId),
+		attribute.Int("cart.batch.size", len(operations)),
+		attribute.String("cart.batch.mode", string(mode)),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	if userId == uuid.Nil {
+		return nil, model.NewValidationError("user_id", "user_id must be not nil")
+	}
+
+	if len(operations) == 0 {
+		return nil, model.NewValidationError("items", "operations must be not empty")
+	}
+
+	if len(operations) > model.MaxBatchOperations {
+		return nil, model.NewValidationError("items", fmt.Sprintf("operations must be not more than %d", model.MaxBatchOperations))
+	}
+
+	if mode != model.BatchModeAllOrNothing && mode != model.BatchModeBestEffort {
+		return nil, model.NewValidationError("mode", fmt.Sprintf("unknown batch mode %q", mode))
+	}
+
+	results := make([]model.CartItemOperationResult, len(operations))
+	for i, operation := range operations {
+		results[i] = model.CartItemOperationResult{
+			Operation: operation,
+			Err:       validateOperation(operation),
+		}
+	}
+
+	productErrors := s.validateProducts(ctx, results)
+	for i := range results {
+		if results[i].Err == nil && results[i].Operation.Action == model.CartItemActionAdd {
+			results[i].Err = productErrors[results[i].Operation.SkuId]
+		}
+	}
+
+	if mode == model.BatchModeAllOrNothing && hasFailedOperations(results) {
+		return results, model.ErrBatchNotApplied
+	}
+
+	// Остатки запрашиваются параллельно и до блокировки корзины, под блокировкой они только сравниваются
+	stock := batchStock{}
+	stock.counts, stock.errors = s.lookupAvailableCounts(ctx, addOperationSkus(results))
+
+	err = s.withinCartTransaction(ctx, userId, func(ctx context.Context) error {
+		cartItems, err := s.cartRepository.GetCartItemsByUserId(ctx, userId)
+		if err != nil {
+			return fmt.Errorf("cartRepository.GetCartItemsByUserId :%w", err)
+		}
+
+		counts := cartCounts(cartItems)
+
+		if mode == model.BatchModeAllOrNothing {
+			// Сначала все операции проверяются на копии корзины и только затем записываются:
+			// in-memory транзакция не откатывает уже примененные операции
+			for i := range results {
+				newCount, err := s.checkOperation(counts, stock, results[i].Operation)
+				if err != nil {
+					results[i].Err = err
+					return model.ErrBatchNotApplied
+				}
+
+				setCount(counts, results[i].Operation.SkuId, newCount)
+			}
+
+			for i := range results {
+				if err := s.writeOperation(ctx, userId, results[i].Operation); err != nil {
+					results[i].Err = err
+					return model.ErrBatchNotApplied
+				}
+			}
+
+			return nil
+		}
+
+		for i := range results {
+			if results[i].Err != nil {
+				continue
+			}
+
+			newCount, err := s.checkOperation(counts, stock, results[i].Operation)
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+
+			// Каждая операция выполняется в собственном savepoint, чтобы ошибка одной не откатывала остальные
+			results[i].Err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				return s.writeOperation(ctx, userId, results[i].Operation)
+			})
+			if results[i].Err == nil {
+				setCount(counts, results[i].Operation.SkuId, newCount)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return results, err
+	}
+
+	for i := range results {
+		results[i].Applied = results[i].Err == nil
+	}
+
+	return results, nil
+}
+
+// batchStock остатки товаров пакета, запрошенные до начала транзакции
+type batchStock struct {
+	counts map[uint64]uint64
+	errors map[uint64]error
+}
+
+// check проверяет, что товара sku на складе не меньше requested
+func (b batchStock) check(sku uint64, requested uint64) error {
+	if err, ok := b.errors[sku]; ok {
+		return err
+	}
+
+	if available := b.counts[sku]; available < requested {
+		return model.NewInsufficientStockError(sku, available, requested)
+	}
+
+	return nil
+}
+
+// checkOperation проверяет лимиты и остатки для операции над корзиной с количествами counts
+// и возвращает количество sku после операции
+func (s *CartService) checkOperation(counts map[uint64]uint64, stock batchStock, operation model.CartItemOperation) (uint64, error) {
+	if operation.Action == model.CartItemActionRemove {
+		return 0, nil
+	}
+
+	count := counts[operation.SkuId] + uint64(operation.Count)
+	if err := s.checkLimits(counts, operation.SkuId, count); err != nil {
+		return 0, err
+	}
+
+	if err := stock.check(operation.SkuId, count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (s *CartService) writeOperation(ctx context.Context, userId uuid.UUID, operation model.CartItemOperation) error {
+	switch operation.Action {
+	case model.CartItemActionRemove:
+		removed, err := s.cartRepository.RemoveCartItem(ctx, userId, operation.SkuId)
+		if err != nil {
+			return fmt.Errorf("cartRepository.RemoveCartItem :%w", err)
+		}
+
+		if !removed {
+			return nil
+		}
+
+		return s.addEvent(ctx, model.CartEvent{
+			Type:   model.CartEventItemRemoved,
+			UserId: userId,
+			SkuId:  operation.SkuId,
+		})
+	default:
+		result, err := s.cartRepository.UpsertCartItem(ctx, model.CartItem{
+			UserId: userId,
+			SkuId:  operation.SkuId,
+			Count:  operation.Count,
+		})
+		if err != nil {
+			return fmt.Errorf("cartRepository.UpsertCartItem :%w", err)
+		}
+
+		eventType := model.CartEventItemCountChanged
+		if result.Count == operation.Count {
+			eventType = model.CartEventItemAdded
+		}
+
+		return s.addEvent(ctx, model.CartEvent{
+			Type:   eventType,
+			UserId: userId,
+			SkuId:  operation.SkuId,
+			Count:  result.Count,
+		})
+	}
+}
+
+func setCount(counts map[uint64]uint64, sku uint64, count uint64) {
+	if count == 0 {
+		delete(counts, sku)
+		return
+	}
+
+	counts[sku] = count
+}
+
+// validateProducts одним запросом проверяет существование товаров для операций добавления.
+func (s *CartService) validateProducts(ctx context.Context, results []model.CartItemOperationResult) map[uint64]error {
+	skus := addOperationSkus(results)
+
+	productErrors := make(map[uint64]error, len(skus))
+	if len(skus) == 0 {
+		return productErrors
+	}
+
+	_, err := s.productService.GetProductsBySkus(ctx, skus)
+	if err == nil {
+		return productErrors
+	}
+
+	var lookupErr *model.ProductsLookupError
+	if !errors.As(err, &lookupErr) {
+		// Сервис товаров недоступен целиком, проверка не прошла ни для одного товара
+		for _, sku := range skus {
+			productErrors[sku] = fmt.Errorf("productService.GetProductsBySkus: %w", err)
+		}
+
+		return productErrors
+	}
+
+	for sku, err := range lookupErr.Errors {
+		productErrors[sku] = fmt.Errorf("productService.GetProductsBySkus: %w", err)
+	}
+
+	return productErrors
+}
+
+// addOperationSkus возвращает sku корректных операций добавления без повторов
+func addOperationSkus(results []model.CartItemOperationResult) []uint64 {
+	skus := make([]uint64, 0, len(results))
+	seen := make(map[uint64]struct{}, len(results))
+	for _, result := range results {
+		if result.Err != nil || result.Operation.Action != model.CartItemActionAdd {
+			continue
+		}
+
+		if _, ok := seen[result.Operation.SkuId]; ok {
+			continue
+		}
+
+		seen[result.Operation.SkuId] = struct{}{}
+		skus = append(skus, result.Operation.SkuId)
+	}
+
+	return skus
+}
+
+func validateOperation(operation model.CartItemOperation) error {
+	if operation.SkuId < 1 {
+		return model.NewValidationError("sku", "sku must be greater than zero")
+	}
+
+	switch operation.Action {
+	case model.CartItemActionAdd:
+		if operation.Count < 1 {
+			return model.NewValidationError("count", "count must be greater than zero")
+		}
+	case model.CartItemActionRemove:
+	default:
+		return model.NewValidationError("action", fmt.Sprintf("unknown action %q", operation.Action))
+	}
+
+	return nil
+}
+
+func hasFailedOperations(results []model.CartItemOperationResult) bool {
+	for _, result := range results {
+		if result.Err != nil {
+			return true
+		}
+	}
+
+	return false
+}
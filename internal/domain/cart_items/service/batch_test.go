@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+func newBatchProductService() *stubProductService {
+	return &stubProductService{
+		getFn: func(ctx context.Context, sku uint64) (*model.Product, error) {
+			if sku == 404 {
+				return nil, model.ErrProductNotFound
+			}
+			return &model.Product{Sku: sku}, nil
+		},
+	}
+}
+
+func TestCartService_ApplyBatch_AllOrNothing_OK(t *testing.T) {
+	var upserted []uint64
+	var removed []uint64
+	cartRepo := &stubCartRepo{
+		upsertFn: func(ctx context.Context, item model.CartItem) (*model.CartItem, error) {
+			upserted = append(upserted, item.SkuId)
+			return &item, nil
+		},
+		removeFn: func(ctx context.Context, uid uuid.UUID, sku uint64) (bool, error) {
+			removed = append(removed, sku)
+			return true, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, newBatchProductService(), &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	results, err := svc.ApplyBatch(context.Background(), uuid.New(), []model.CartItemOperation{
+		{Action: model.CartItemActionAdd, SkuId: 1, Count: 2},
+		{Action: model.CartItemActionAdd, SkuId: 2, Count: 1},
+		{Action: model.CartItemActionRemove, SkuId: 3},
+	}, model.BatchModeAllOrNothing)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for _, result := range results {
+		require.True(t, result.Applied)
+		require.NoError(t, result.Err)
+	}
+	require.Equal(t, []uint64{1, 2}, upserted)
+	require.Equal(t, []uint64{3}, removed)
+}
+
+func TestCartService_ApplyBatch_AllOrNothing_Rejected(t *testing.T) {
+	cartRepo := &stubCartRepo{
+		upsertFn: func(ctx context.Context, item model.CartItem) (*model.CartItem, error) {
+			t.Fatal("nothing must be applied")
+			return nil, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, newBatchProductService(), &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	results, err := svc.ApplyBatch(context.Background(), uuid.New(), []model.CartItemOperation{
+		{Action: model.CartItemActionAdd, SkuId: 1, Count: 2},
+		{Action: model.CartItemActionAdd, SkuId: 404, Count: 1},
+		{Action: model.CartItemActionAdd, SkuId: 2, Count: 0},
+	}, model.BatchModeAllOrNothing)
+	require.ErrorIs(t, err, model.ErrBatchNotApplied)
+	require.Len(t, results, 3)
+	require.NoError(t, results[0].Err)
+	require.False(t, results[0].Applied)
+	require.ErrorIs(t, results[1].Err, model.ErrProductNotFound)
+	require.Error(t, results[2].Err)
+}
+
+func TestCartService_ApplyBatch_BestEffort(t *testing.T) {
+	var upserted []uint64
+	cartRepo := &stubCartRepo{
+		upsertFn: func(ctx context.Context, item model.CartItem) (*model.CartItem, error) {
+			upserted = append(upserted, item.SkuId)
+			return &item, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, newBatchProductService(), &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	results, err := svc.ApplyBatch(context.Background(), uuid.New(), []model.CartItemOperation{
+		{Action: model.CartItemActionAdd, SkuId: 1, Count: 2},
+		{Action: model.CartItemActionAdd, SkuId: 404, Count: 1},
+		{Action: "replace", SkuId: 3, Count: 1},
+	}, model.BatchModeBestEffort)
+	require.NoError(t, err)
+	require.True(t, results[0].Applied)
+	require.False(t, results[1].Applied)
+	require.ErrorIs(t, results[1].Err, model.ErrProductNotFound)
+	require.False(t, results[2].Applied)
+	require.Error(t, results[2].Err)
+	require.Equal(t, []uint64{1}, upserted)
+}
+
+func TestCartService_ApplyBatch_LimitsSeePreviousOperations(t *testing.T) {
+	counts := map[uint64]uint32{}
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			items := make([]model.CartItem, 0, len(counts))
+			for sku, count := range counts {
+				items = append(items, model.CartItem{UserId: uid, SkuId: sku, Count: count})
+			}
+			return items, nil
+		},
+		upsertFn: func(ctx context.Context, item model.CartItem) (*model.CartItem, error) {
+			counts[item.SkuId] += item.Count
+			item.Count = counts[item.SkuId]
+			return &item, nil
+		},
+	}
+
+	limits := model.CartLimits{MaxTotalUnits: 5}
+	svc := NewCartService(cartRepo, newBatchProductService(), &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, limits)
+
+	results, err := svc.ApplyBatch(context.Background(), uuid.New(), []model.CartItemOperation{
+		{Action: model.CartItemActionAdd, SkuId: 1, Count: 3},
+		{Action: model.CartItemActionAdd, SkuId: 2, Count: 3},
+		{Action: model.CartItemActionAdd, SkuId: 2, Count: 2},
+	}, model.BatchModeBestEffort)
+	require.NoError(t, err)
+	require.True(t, results[0].Applied)
+	require.False(t, results[1].Applied)
+	require.ErrorIs(t, results[1].Err, model.ErrCartLimitExceeded)
+	require.True(t, results[2].Applied)
+	require.Equal(t, map[uint64]uint32{1: 3, 2: 2}, counts)
+}
+
+func TestCartService_ApplyBatch_AllOrNothing_WritesNothingOnLateFailure(t *testing.T) {
+	// stubTransactor, как и in-memory транзакция, не откатывает изменения,
+	// поэтому ни одна операция не должна быть записана до проверки всех остальных
+	cartRepo := &stubCartRepo{
+		upsertFn: func(ctx context.Context, item model.CartItem) (*model.CartItem, error) {
+			t.Fatal("cart must not be changed")
+			return nil, nil
+		},
+		removeFn: func(ctx context.Context, uid uuid.UUID, sku uint64) (bool, error) {
+			t.Fatal("cart must not be changed")
+			return true, nil
+		},
+	}
+	outboxRepo := &stubOutboxRepo{}
+	svc := NewCartService(cartRepo, newBatchProductService(), stockCounts(map[uint64]uint64{1: 10, 2: 1}), nil, nil, outboxRepo, &stubTransactor{}, model.CartLimits{})
+
+	results, err := svc.ApplyBatch(context.Background(), uuid.New(), []model.CartItemOperation{
+		{Action: model.CartItemActionAdd, SkuId: 1, Count: 2},
+		{Action: model.CartItemActionRemove, SkuId: 3},
+		{Action: model.CartItemActionAdd, SkuId: 2, Count: 2},
+	}, model.BatchModeAllOrNothing)
+	require.ErrorIs(t, err, model.ErrBatchNotApplied)
+	require.NoError(t, results[0].Err)
+	require.NoError(t, results[1].Err)
+	require.ErrorIs(t, results[2].Err, model.ErrInsufficientStock)
+	require.Empty(t, outboxRepo.events)
+}
+
+func TestCartService_ApplyBatch_InsufficientStock(t *testing.T) {
+	var upserted []uint64
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			return []model.CartItem{{UserId: uid, SkuId: 1, Count: 4}}, nil
+		},
+		upsertFn: func(ctx context.Context, item model.CartItem) (*model.CartItem, error) {
+			upserted = append(upserted, item.SkuId)
+			return &item, nil
+		},
+	}
+	svc := NewCartService(cartRepo, newBatchProductService(), stockCounts(map[uint64]uint64{1: 5, 2: 5}), nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	// Остаток проверяется с учетом того, что уже лежит в корзине
+	results, err := svc.ApplyBatch(context.Background(), uuid.New(), []model.CartItemOperation{
+		{Action: model.CartItemActionAdd, SkuId: 1, Count: 2},
+		{Action: model.CartItemActionAdd, SkuId: 2, Count: 5},
+	}, model.BatchModeBestEffort)
+	require.NoError(t, err)
+	require.False(t, results[0].Applied)
+	require.ErrorIs(t, results[0].Err, model.ErrInsufficientStock)
+	require.True(t, results[1].Applied)
+	require.Equal(t, []uint64{2}, upserted)
+}
+
+func TestCartService_ApplyBatch_TooManyOperations(t *testing.T) {
+	svc := NewCartService(&stubCartRepo{}, newBatchProductService(), &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	operations := make([]model.CartItemOperation, model.MaxBatchOperations+1)
+	for i := range operations {
+		operations[i] = model.CartItemOperation{Action: model.CartItemActionAdd, SkuId: uint64(i + 1), Count: 1}
+	}
+
+	_, err := svc.ApplyBatch(context.Background(), uuid.New(), operations, model.BatchModeBestEffort)
+
+	var domainErr *model.Error
+	require.ErrorAs(t, err, &domainErr)
+	require.Equal(t, model.ErrorKindValidation, domainErr.Kind)
+	require.Equal(t, "items", domainErr.Details["field"])
+}
+
+func TestCartService_ApplyBatch_LooksUpStockBeforeLock(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	record := func(call string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call)
+	}
+
+	cartRepo := &stubCartRepo{
+		versionFn: func(ctx context.Context, uid uuid.UUID) (uint64, error) {
+			record("lock")
+			return 0, nil
+		},
+		upsertFn: func(ctx context.Context, item model.CartItem) (*model.CartItem, error) {
+			return &item, nil
+		},
+	}
+
+	stockSrv := &stubStockService{
+		countFn: func(ctx context.Context, sku uint64) (uint64, error) {
+			record("stock")
+			if sku == 2 {
+				return 0, model.ErrStockServiceUnavailable
+			}
+			return 10, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, newBatchProductService(), stockSrv, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	// Остаток каждого sku запрашивается один раз, даже если sku встречается в нескольких операциях
+	results, err := svc.ApplyBatch(context.Background(), uuid.New(), []model.CartItemOperation{
+		{Action: model.CartItemActionAdd, SkuId: 1, Count: 2},
+		{Action: model.CartItemActionAdd, SkuId: 2, Count: 1},
+		{Action: model.CartItemActionAdd, SkuId: 1, Count: 3},
+	}, model.BatchModeBestEffort)
+	require.NoError(t, err)
+	require.True(t, results[0].Applied)
+	require.ErrorIs(t, results[1].Err, model.ErrStockServiceUnavailable)
+	require.True(t, results[2].Applied)
+	require.Equal(t, []string{"stock", "stock", "lock"}, calls)
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+)
+
+const (
+	defaultExpirationInterval  = 10 * time.Minute
+	defaultExpirationBatchSize = 100
+)
+
+type ExpiredCartRepository interface {
+	RemoveExpiredCarts(ctx context.Context, idleBefore time.Time, limit int) ([]uuid.UUID, error)
+}
+
+// CartExpirer удаляет корзины, которые не менялись дольше ttl, и пишет в outbox событие cart_expired
+// для каждой из них
+type CartExpirer struct {
+	cartRepository   ExpiredCartRepository
+	outboxRepository OutboxRepository
+	transactor       Transactor
+	ttl              time.Duration
+	interval         time.Duration
+	batchSize        int
+	now              func() time.Time
+}
+
+// NewCartExpirer нулевой ttl отключает удаление; now позволяет подменить часы в тестах, nil означает time.Now
+func NewCartExpirer(
+	cartRepository ExpiredCartRepository,
+	outboxRepository OutboxRepository,
+	transactor Transactor,
+	ttl time.Duration,
+	interval time.Duration,
+	batchSize int,
+	now func() time.Time,
+) *CartExpirer {
+	if interval <= 0 {
+		interval = defaultExpirationInterval
+	}
+
+	if batchSize <= 0 {
+		batchSize = defaultExpirationBatchSize
+	}
+
+	if now == nil {
+		now = time.Now
+	}
+
+	return &CartExpirer{
+		cartRepository:   cartRepository,
+		outboxRepository: outboxRepository,
+		transactor:       transactor,
+		ttl:              ttl,
+		interval:         interval,
+		batchSize:        batchSize,
+		now:              now,
+	}
+}
+
+// Run удаляет истекшие корзины, пока не будет отменен ctx.
+func (e *CartExpirer) Run(ctx context.Context) {
+	if e.ttl <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			expired, err := e.ExpireBatch(ctx)
+			if err != nil {
+				logger.FromContext(ctx).Error("cart expirer: expire batch failed", "error", err)
+				break
+			}
+
+			if expired > 0 {
+				logger.FromContext(ctx).Info("cart expirer: carts expired", "count", expired)
+			}
+
+			if expired < e.batchSize {
+				break
+			}
+		}
+	}
+}
+
+// ExpireBatch удаляет не более batchSize истекших корзин и возвращает их количество.
+// Удаление и события выполняются в одной транзакции.
+func (e *CartExpirer) ExpireBatch(ctx context.Context) (int, error) {
+	now := e.now()
+	expired := 0
+
+	err := e.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		userIds, err := e.cartRepository.RemoveExpiredCarts(ctx, now.Add(-e.ttl), e.batchSize)
+		if err != nil {
+			return fmt.Errorf("cartRepository.RemoveExpiredCarts: %w", err)
+		}
+
+		for _, userId := range userIds {
+			err = e.outboxRepository.AddCartEvent(ctx, model.CartEvent{
+				Type:       model.CartEventCartExpired,
+				UserId:     userId,
+				OccurredAt: now.UTC(),
+			})
+			if err != nil {
+				return fmt.Errorf("outboxRepository.AddCartEvent: %w", err)
+			}
+		}
+
+		expired = len(userIds)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return expired, nil
+}
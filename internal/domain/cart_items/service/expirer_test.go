@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+type stubExpiredCartRepo struct {
+	removeExpiredFn func(ctx context.Context, idleBefore time.Time, limit int) ([]uuid.UUID, error)
+}
+
+func (s *stubExpiredCartRepo) RemoveExpiredCarts(ctx context.Context, idleBefore time.Time, limit int) ([]uuid.UUID, error) {
+	return s.removeExpiredFn(ctx, idleBefore, limit)
+}
+
+func TestCartExpirer_ExpireBatch(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	userIds := []uuid.UUID{uuid.New(), uuid.New()}
+
+	cartRepo := &stubExpiredCartRepo{
+		removeExpiredFn: func(ctx context.Context, idleBefore time.Time, limit int) ([]uuid.UUID, error) {
+			require.Equal(t, now.Add(-24*time.Hour), idleBefore)
+			require.Equal(t, 50, limit)
+			return userIds, nil
+		},
+	}
+	outboxRepo := &stubOutboxRepo{}
+
+	expirer := NewCartExpirer(cartRepo, outboxRepo, &stubTransactor{}, 24*time.Hour, time.Minute, 50, func() time.Time { return now })
+
+	expired, err := expirer.ExpireBatch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, expired)
+
+	require.Len(t, outboxRepo.events, 2)
+	for i, event := range outboxRepo.events {
+		require.Equal(t, model.CartEventCartExpired, event.Type)
+		require.Equal(t, userIds[i], event.UserId)
+		require.Equal(t, now, event.OccurredAt)
+	}
+}
+
+func TestCartExpirer_ExpireBatch_RepoError(t *testing.T) {
+	cartRepo := &stubExpiredCartRepo{
+		removeExpiredFn: func(ctx context.Context, idleBefore time.Time, limit int) ([]uuid.UUID, error) {
+			return nil, errors.New("db down")
+		},
+	}
+	outboxRepo := &stubOutboxRepo{}
+
+	expirer := NewCartExpirer(cartRepo, outboxRepo, &stubTransactor{}, time.Hour, 0, 0, nil)
+
+	_, err := expirer.ExpireBatch(context.Background())
+	require.Error(t, err)
+	require.Empty(t, outboxRepo.events)
+}
+
+func TestCartExpirer_Run_DrainsFullBatches(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	cartRepo := &stubExpiredCartRepo{
+		removeExpiredFn: func(ctx context.Context, idleBefore time.Time, limit int) ([]uuid.UUID, error) {
+			calls++
+
+			// Две полные пачки подряд, затем неполная завершает итерацию
+			if calls <= 2 {
+				return []uuid.UUID{uuid.New(), uuid.New()}, nil
+			}
+
+			cancel()
+			return []uuid.UUID{uuid.New()}, nil
+		},
+	}
+
+	expirer := NewCartExpirer(cartRepo, &stubOutboxRepo{}, &stubTransactor{}, time.Hour, time.Millisecond, 2, nil)
+
+	done := make(chan struct{})
+	go func() {
+		expirer.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expirer did not stop")
+	}
+	require.Equal(t, 3, calls)
+}
+
+func TestCartExpirer_Run_DisabledWithoutTtl(t *testing.T) {
+	expirer := NewCartExpirer(&stubExpiredCartRepo{}, &stubOutboxRepo{}, &stubTransactor{}, 0, time.Millisecond, 1, nil)
+
+	// При нулевом ttl Run возвращается сразу, не обращаясь к репозиторию
+	expirer.Run(context.Background())
+}
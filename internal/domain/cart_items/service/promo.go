@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ApplyPromoCode применяет промокод к корзине, заменяя ранее примененный.
+// Условия промокода проверяются по текущему содержимому корзины.
+func (s *CartService) ApplyPromoCode(ctx context.Context, userId uuid.UUID, code string) (err error) {
+	ctx, span := tracer.Start(ctx, "CartService.ApplyPromoCode", trace.WithAttributes(tracing.UserId(userId)))
+	defer func() { tracing.End(span, err) }()
+
+	if userId == uuid.Nil {
+		return model.NewValidationError("user_id", "user_id must be not nil")
+	}
+
+	code = model.NormalizePromoCode(code)
+	if code == "" {
+		return model.NewValidationError("code", "code must be not empty")
+	}
+
+	cartItems, err := s.cartRepository.GetCartItemsByUserId(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("cartRepository.GetCartItemsByUserId :%w", err)
+	}
+
+	lines, err := s.getCartLines(ctx, cartItems)
+	if err != nil {
+		return err
+	}
+
+	if _, err = s.promoEngine.Apply(code, lines); err != nil {
+		return fmt.Errorf("promoEngine.Apply :%w", err)
+	}
+
+	return s.withinCartTransaction(ctx, userId, func(ctx context.Context) error {
+		if err := s.cartRepository.SetCartPromoCode(ctx, userId, code); err != nil {
+			return fmt.Errorf("cartRepository.SetCartPromoCode :%w", err)
+		}
+
+		return s.addEvent(ctx, model.CartEvent{
+			Type:      model.CartEventPromoCodeApplied,
+			UserId:    userId,
+			PromoCode: code,
+		})
+	})
+}
+
+// RemovePromoCode снимает промокод с корзины. Если промокода нет, ничего не делает.
+func (s *CartService) RemovePromoCode(ctx context.Context, userId uuid.UUID) (err error) {
+	ctx, span := tracer.Start(ctx, "CartService.RemovePromoCode", trace.WithAttributes(tracing.UserId(userId)))
+	defer func() { tracing.End(span, err) }()
+
+	if userId == uuid.Nil {
+		return model.NewValidationError("user_id", "user_id must be not nil")
+	}
+
+	return s.withinCartTransaction(ctx, userId, func(ctx context.Context) error {
+		code, err := s.cartRepository.GetCartPromoCode(ctx, userId)
+		if err != nil {
+			return fmt.Errorf("cartRepository.GetCartPromoCode :%w", err)
+		}
+
+		if code == "" {
+			return nil
+		}
+
+		if err = s.cartRepository.SetCartPromoCode(ctx, userId, ""); err != nil {
+			return fmt.Errorf("cartRepository.SetCartPromoCode :%w", err)
+		}
+
+		return s.addEvent(ctx, model.CartEvent{
+			Type:      model.CartEventPromoCodeRemoved,
+			UserId:    userId,
+			PromoCode: code,
+		})
+	})
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+func newPromoCartRepo(items []model.CartItem, promoCode *string) *stubCartRepo {
+	return &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			return items, nil
+		},
+		setPromoFn: func(ctx context.Context, uid uuid.UUID, code string) error {
+			*promoCode = code
+			return nil
+		},
+		getPromoFn: func(ctx context.Context, uid uuid.UUID) (string, error) {
+			return *promoCode, nil
+		},
+	}
+}
+
+func pricedProducts() *stubProductService {
+	return &stubProductService{
+		getFn: func(ctx context.Context, sku uint64) (*model.Product, error) {
+			return &model.Product{Sku: sku, Price: float64(sku) * 100}, nil
+		},
+	}
+}
+
+func TestCartService_ApplyPromoCode_OK(t *testing.T) {
+	userId := uuid.New()
+	promoCode := ""
+	cartRepo := newPromoCartRepo([]model.CartItem{{UserId: userId, SkuId: 1, Count: 2}}, &promoCode)
+
+	promoEngine := &stubPromoEngine{
+		applyFn: func(code string, lines []model.CartLine) ([]model.CartDiscount, error) {
+			require.Equal(t, "WELCOME10", code)
+			require.Len(t, lines, 1)
+			require.Equal(t, 200.0, lines[0].TotalPrice)
+			return nil, nil
+		},
+	}
+
+	outboxRepo := &stubOutboxRepo{}
+	svc := NewCartService(cartRepo, pricedProducts(), &stubStockService{}, nil, promoEngine, outboxRepo, &stubTransactor{}, model.CartLimits{})
+
+	require.NoError(t, svc.ApplyPromoCode(context.Background(), userId, " welcome10 "))
+	require.Equal(t, "WELCOME10", promoCode)
+	require.Len(t, outboxRepo.events, 1)
+	require.Equal(t, model.CartEventPromoCodeApplied, outboxRepo.events[0].Type)
+	require.Equal(t, "WELCOME10", outboxRepo.events[0].PromoCode)
+}
+
+func TestCartService_ApplyPromoCode_Rejected(t *testing.T) {
+	for _, engineErr := range []error{model.ErrPromoCodeNotFound, model.NewPromoCodeNotApplicableError(3000, 200)} {
+		promoCode := ""
+		cartRepo := newPromoCartRepo([]model.CartItem{{SkuId: 1, Count: 2}}, &promoCode)
+
+		promoEngine := &stubPromoEngine{
+			applyFn: func(code string, lines []model.CartLine) ([]model.CartDiscount, error) {
+				return nil, engineErr
+			},
+		}
+
+		outboxRepo := &stubOutboxRepo{}
+		svc := NewCartService(cartRepo, pricedProducts(), &stubStockService{}, nil, promoEngine, outboxRepo, &stubTransactor{}, model.CartLimits{})
+
+		err := svc.ApplyPromoCode(context.Background(), uuid.New(), "MINUS500")
+		require.ErrorIs(t, err, engineErr)
+		require.Empty(t, promoCode)
+		require.Empty(t, outboxRepo.events)
+	}
+}
+
+func TestCartService_ApplyPromoCode_EmptyCode(t *testing.T) {
+	svc := NewCartService(&stubCartRepo{}, nil, nil, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	err := svc.ApplyPromoCode(context.Background(), uuid.New(), "  ")
+	var domainErr *model.Error
+	require.ErrorAs(t, err, &domainErr)
+	require.Equal(t, model.ErrorKindValidation, domainErr.Kind)
+}
+
+func TestCartService_RemovePromoCode(t *testing.T) {
+	promoCode := "WELCOME10"
+	cartRepo := newPromoCartRepo(nil, &promoCode)
+
+	outboxRepo := &stubOutboxRepo{}
+	svc := NewCartService(cartRepo, nil, nil, nil, nil, outboxRepo, &stubTransactor{}, model.CartLimits{})
+
+	require.NoError(t, svc.RemovePromoCode(context.Background(), uuid.New()))
+	require.Empty(t, promoCode)
+	require.Len(t, outboxRepo.events, 1)
+	require.Equal(t, model.CartEventPromoCodeRemoved, outboxRepo.events[0].Type)
+
+	// Повторное снятие ничего не меняет
+	require.NoError(t, svc.RemovePromoCode(context.Background(), uuid.New()))
+	require.Len(t, outboxRepo.events, 1)
+}
+
+func TestCartService_GetCart_WithPromoCode(t *testing.T) {
+	promoCode := "COMBO"
+	cartRepo := newPromoCartRepo([]model.CartItem{
+		{SkuId: 1, Count: 3},
+		{SkuId: 2, Count: 1},
+	}, &promoCode)
+
+	promoEngine := &stubPromoEngine{
+		applyFn: func(code string, lines []model.CartLine) ([]model.CartDiscount, error) {
+			return []model.CartDiscount{
+				{Code: code, Rule: model.PromoRuleBuyXGetY, SkuId: 1, Amount: 100},
+				{Code: code, Rule: model.PromoRuleAmountOff, Amount: 33.33},
+			}, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, pricedProducts(), &stubStockService{}, nil, promoEngine, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	cart, err := svc.GetCart(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.Equal(t, 500.0, cart.Subtotal)
+	require.Equal(t, "COMBO", cart.PromoCode)
+	require.Len(t, cart.Discounts, 2)
+	require.Equal(t, 366.67, cart.TotalPrice)
+}
+
+func TestCartService_GetCart_PromoCodeNotApplicable(t *testing.T) {
+	promoCode := "MINUS500"
+	cartRepo := newPromoCartRepo([]model.CartItem{{SkuId: 1, Count: 1}}, &promoCode)
+
+	promoEngine := &stubPromoEngine{
+		applyFn: func(code string, lines []model.CartLine) ([]model.CartDiscount, error) {
+			return nil, model.NewPromoCodeNotApplicableError(3000, 100)
+		},
+	}
+
+	svc := NewCartService(cartRepo, pricedProducts(), &stubStockService{}, nil, promoEngine, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	cart, err := svc.GetCart(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.Equal(t, "MINUS500", cart.PromoCode)
+	require.Empty(t, cart.Discounts)
+	require.Equal(t, 100.0, cart.TotalPrice)
+}
+
+func TestCartService_Checkout_ClearsPromoCode(t *testing.T) {
+	promoCode := "WELCOME10"
+	cartRepo := newPromoCartRepo([]model.CartItem{{SkuId: 1, Count: 1}}, &promoCode)
+	cartRepo.removeAllFn = func(ctx context.Context, uid uuid.UUID) (bool, error) {
+		return true, nil
+	}
+
+	orderSrv := &stubOrderService{
+		createFn: func(ctx context.Context, uid uuid.UUID, orderItems []model.CartItem) (int64, error) {
+			return 1, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, existingProducts(), &stubStockService{}, orderSrv, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	_, err := svc.Checkout(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.Empty(t, promoCode)
+}
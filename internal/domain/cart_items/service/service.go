@@ -4,66 +4,136 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const stockLookupConcurrency = 8
+
+var tracer = tracing.Tracer("github.com/jva44ka/ozon-simulator-go-cart/internal/domain/cart_items/service")
+
 type CartRepository interface {
 	AddCartItem(_ context.Context, cartItem model.CartItem) (*model.CartItem, error)
+	UpsertCartItem(_ context.Context, cartItem model.CartItem) (*model.CartItem, error)
 	UpdateCartItem(_ context.Context, id uint64, cartItem model.CartItem) (*model.CartItem, error)
 	GetCartItemsByUserId(_ context.Context, userId uuid.UUID) ([]model.CartItem, error)
 	GetCartItem(_ context.Context, userId uuid.UUID, sku uint64) (*model.CartItem, error)
-	RemoveCartItem(_ context.Context, userId uuid.UUID, sku uint64) error
-	RemoveAllCartItemsByUserId(_ context.Context, userId uuid.UUID) error
+	RemoveCartItem(_ context.Context, userId uuid.UUID, sku uint64) (bool, error)
+	RemoveAllCartItemsByUserId(_ context.Context, userId uuid.UUID) (bool, error)
+	GetCartVersion(_ context.Context, userId uuid.UUID) (uint64, error)
+	LockCartVersion(_ context.Context, userId uuid.UUID) (uint64, error)
+	SetCartPromoCode(_ context.Context, userId uuid.UUID, code string) error
+	GetCartPromoCode(_ context.Context, userId uuid.UUID) (string, error)
 }
 
 type ProductService interface {
 	GetProductBySku(ctx context.Context, sku uint64) (*model.Product, error)
+	GetProductsBySkus(ctx context.Context, skus []uint64) (map[uint64]*model.Product, error)
+}
+
+type StockService interface {
+	GetAvailableCount(ctx context.Context, sku uint64) (uint64, error)
+}
+
+type OrderService interface {
+	// CreateOrder создает заказ; повтор с тем же idempotencyKey возвращает ранее созданный заказ
+	CreateOrder(ctx context.Context, userId uuid.UUID, items []model.CartItem, idempotencyKey string) (int64, error)
+}
+
+// PromoEngine рассчитывает скидки для отображения в корзине. В LOMS скидки не передаются:
+// заказ оформляется по полной цене, а промокод сбрасывается вместе с корзиной
+type PromoEngine interface {
+	Apply(code string, lines []model.CartLine) ([]model.CartDiscount, error)
+}
+
+type OutboxRepository interface {
+	AddCartEvent(ctx context.Context, event model.CartEvent) error
+}
+
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type CartService struct {
-	cartRepository CartRepository
-	productService ProductService
+	cartRepository   CartRepository
+	productService   ProductService
+	stockService     StockService
+	orderService     OrderService
+	promoEngine      PromoEngine
+	outboxRepository OutboxRepository
+	transactor       Transactor
+	limits           model.CartLimits
 }
 
-func NewCartService(cartRepository CartRepository, productService ProductService) *CartService {
-	return &CartService{cartRepository: cartRepository, productService: productService}
+func NewCartService(
+	cartRepository CartRepository,
+	productService ProductService,
+	stockService StockService,
+	orderService OrderService,
+	promoEngine PromoEngine,
+	outboxRepository OutboxRepository,
+	transactor Transactor,
+	limits model.CartLimits,
+) *CartService {
+	return &CartService{
+		cartRepository:   cartRepository,
+		productService:   productService,
+		stockService:     stockService,
+		orderService:     orderService,
+		promoEngine:      promoEngine,
+		outboxRepository: outboxRepository,
+		transactor:       transactor,
+		limits:           limits,
+	}
 }
 
-func (s *CartService) AddProduct(ctx context.Context, userId uuid.UUID, sku uint64, count uint32) error {
+func (s *CartService) AddProduct(ctx context.Context, userId uuid.UUID, sku uint64, count uint32) (err error) {
+	ctx, span := tracer.Start(ctx, "CartService.AddProduct", trace.WithAttributes(tracing.UserId(userId), tracing.Sku(sku)))
+	defer func() { tracing.End(span, err) }()
+
 	if sku < 1 {
-		return errors.New("sku must be greater than zero")
+		return model.NewValidationError("sku", "sku must be greater than zero")
 	}
 
 	if userId == uuid.Nil {
-		return errors.New("user_id must be not nil")
+		return model.NewValidationError("user_id", "user_id must be not nil")
 	}
 
 	if count < 1 {
-		return errors.New("count must be greater than zero")
+		return model.NewValidationError("count", "count must be greater than zero")
 	}
 
-	// TODO: refactor this
 	existingCartItem, err := s.cartRepository.GetCartItem(ctx, userId, sku)
 	if err != nil && !errors.Is(err, model.ErrCartItemsNotFound) {
 		return fmt.Errorf("cartRepository.GetCartItem: %w", err)
 	}
-	if existingCartItem != nil {
-		resultCount := existingCartItem.Count + count
-		_, err = s.cartRepository.UpdateCartItem(ctx, existingCartItem.Id, model.CartItem{
-			Count: resultCount,
-		})
 
-		return nil
-	}
+	// Товар, уже лежащий в корзине, повторно не проверяем
+	requested := uint64(count)
+	if existingCartItem == nil {
+		_, err = s.productService.GetProductBySku(ctx, sku)
+		if err != nil {
+			if errors.Is(err, model.ErrProductNotFound) {
+				return fmt.Errorf("productService.GetProductBySku: %w", err)
+			}
 
-	_, err = s.productService.GetProductBySku(ctx, sku)
-	if err != nil {
-		if errors.Is(err, model.ErrProductNotFound) {
-			return fmt.Errorf("productService.GetProductBySku: %w", err)
+			return err
 		}
+	} else {
+		requested += uint64(existingCartItem.Count)
+	}
 
+	if err = s.checkStock(ctx, sku, requested); err != nil {
 		return err
 	}
 
@@ -73,47 +143,236 @@ func (s *CartService) AddProduct(ctx context.Context, userId uuid.UUID, sku uint
 		Count:  count,
 	}
 
-	_, err = s.cartRepository.AddCartItem(ctx, cartItem)
-	if err != nil {
-		return fmt.Errorf("cartRepository.AddCartItem :%w", err)
+	return s.withinCartTransaction(ctx, userId, func(ctx context.Context) error {
+		err := s.checkCartLimits(ctx, userId, sku, func(current uint64) uint64 { return current + uint64(count) })
+		if err != nil {
+			return err
+		}
+
+		result, err := s.cartRepository.UpsertCartItem(ctx, cartItem)
+		if err != nil {
+			return fmt.Errorf("cartRepository.UpsertCartItem :%w", err)
+		}
+
+		eventType := model.CartEventItemCountChanged
+		if result.Count == count {
+			eventType = model.CartEventItemAdded
+		}
+
+		return s.addEvent(ctx, model.CartEvent{
+			Type:   eventType,
+			UserId: userId,
+			SkuId:  sku,
+			Count:  result.Count,
+		})
+	})
+}
+
+// SetProductCount устанавливает абсолютное количество товара в корзине. Нулевое количество удаляет позицию.
+func (s *CartService) SetProductCount(ctx context.Context, userId uuid.UUID, sku uint64, count uint32) (err error) {
+	ctx, span := tracer.Start(ctx, "CartService.SetProductCount", trace.WithAttributes(tracing.UserId(userId), tracing.Sku(sku)))
+	defer func() { tracing.End(span, err) }()
+
+	if sku < 1 {
+		return model.NewValidationError("sku", "sku must be greater than zero")
 	}
 
-	return nil
+	if userId == uuid.Nil {
+		return model.NewValidationError("user_id", "user_id must be not nil")
+	}
+
+	if count == 0 {
+		return s.RemoveProduct(ctx, userId, sku)
+	}
+
+	existingCartItem, err := s.cartRepository.GetCartItem(ctx, userId, sku)
+	if err != nil && !errors.Is(err, model.ErrCartItemsNotFound) {
+		return fmt.Errorf("cartRepository.GetCartItem: %w", err)
+	}
+
+	if existingCartItem == nil {
+		_, err = s.productService.GetProductBySku(ctx, sku)
+		if err != nil {
+			return fmt.Errorf("productService.GetProductBySku: %w", err)
+		}
+	}
+
+	// Уменьшение количества не требует остатка: иначе при нехватке на складе товар нельзя было бы убрать
+	stockChecked := false
+	if existingCartItem == nil || count > existingCartItem.Count {
+		if err = s.checkStock(ctx, sku, uint64(count)); err != nil {
+			return err
+		}
+		stockChecked = true
+	}
+
+	return s.withinCartTransaction(ctx, userId, func(ctx context.Context) error {
+		err := s.checkCartLimits(ctx, userId, sku, func(uint64) uint64 { return uint64(count) })
+		if err != nil {
+			return err
+		}
+
+		existingCartItem, err := s.cartRepository.GetCartItem(ctx, userId, sku)
+		if err != nil && !errors.Is(err, model.ErrCartItemsNotFound) {
+			return fmt.Errorf("cartRepository.GetCartItem: %w", err)
+		}
+
+		// Количество успели уменьшить после первого чтения, и теперь запрос его увеличивает
+		if !stockChecked && existingCartItem != nil && count > existingCartItem.Count {
+			if err = s.checkStock(ctx, sku, uint64(count)); err != nil {
+				return err
+			}
+		}
+
+		if existingCartItem == nil {
+			_, err = s.cartRepository.AddCartItem(ctx, model.CartItem{
+				UserId: userId,
+				SkuId:  sku,
+				Count:  count,
+			})
+			if err != nil {
+				return fmt.Errorf("cartRepository.AddCartItem :%w", err)
+			}
+
+			return s.addEvent(ctx, model.CartEvent{
+				Type:   model.CartEventItemAdded,
+				UserId: userId,
+				SkuId:  sku,
+				Count:  count,
+			})
+		}
+
+		_, err = s.cartRepository.UpdateCartItem(ctx, existingCartItem.Id, model.CartItem{Count: count})
+		if err != nil {
+			return fmt.Errorf("cartRepository.UpdateCartItem :%w", err)
+		}
+
+		return s.addEvent(ctx, model.CartEvent{
+			Type:   model.CartEventItemCountChanged,
+			UserId: userId,
+			SkuId:  sku,
+			Count:  count,
+		})
+	})
 }
 
-func (s *CartService) RemoveProduct(ctx context.Context, userId uuid.UUID, sku uint64) error {
+// DecreaseProductCount уменьшает количество товара в корзине. Если оно становится нулевым, позиция удаляется.
+func (s *CartService) DecreaseProductCount(ctx context.Context, userId uuid.UUID, sku uint64, count uint32) (err error) {
+	ctx, span := tracer.Start(ctx, "CartService.DecreaseProductCount", trace.WithAttributes(tracing.UserId(userId), tracing.Sku(sku)))
+	defer func() { tracing.End(span, err) }()
+
 	if sku < 1 {
-		return errors.New("sku must be greater than zero")
+		return model.NewValidationError("sku", "sku must be greater than zero")
 	}
 
 	if userId == uuid.Nil {
-		return errors.New("user_id must be not nil")
+		return model.NewValidationError("user_id", "user_id must be not nil")
 	}
 
-	err := s.cartRepository.RemoveCartItem(ctx, userId, sku)
-	if err != nil {
-		return fmt.Errorf("cartRepository.RemoveProduct :%w", err)
+	if count < 1 {
+		return model.NewValidationError("count", "count must be greater than zero")
 	}
 
-	return nil
+	return s.withinCartTransaction(ctx, userId, func(ctx context.Context) error {
+		existingCartItem, err := s.cartRepository.GetCartItem(ctx, userId, sku)
+		if err != nil {
+			return fmt.Errorf("cartRepository.GetCartItem: %w", err)
+		}
+
+		if existingCartItem.Count <= count {
+			removed, err := s.cartRepository.RemoveCartItem(ctx, userId, sku)
+			if err != nil {
+				return fmt.Errorf("cartRepository.RemoveCartItem :%w", err)
+			}
+
+			if !removed {
+				return nil
+			}
+
+			return s.addEvent(ctx, model.CartEvent{
+				Type:   model.CartEventItemRemoved,
+				UserId: userId,
+				SkuId:  sku,
+			})
+		}
+
+		resultCount := existingCartItem.Count - count
+		_, err = s.cartRepository.UpdateCartItem(ctx, existingCartItem.Id, model.CartItem{Count: resultCount})
+		if err != nil {
+			return fmt.Errorf("cartRepository.UpdateCartItem :%w", err)
+		}
+
+		return s.addEvent(ctx, model.CartEvent{
+			Type:   model.CartEventItemCountChanged,
+			UserId: userId,
+			SkuId:  sku,
+			Count:  resultCount,
+		})
+	})
 }
 
-func (s *CartService) RemoveAllProducts(ctx context.Context, userId uuid.UUID) error {
+func (s *CartService) RemoveProduct(ctx context.Context, userId uuid.UUID, sku uint64) (err error) {
+	ctx, span := tracer.Start(ctx, "CartService.RemoveProduct", trace.WithAttributes(tracing.UserId(userId), tracing.Sku(sku)))
+	defer func() { tracing.End(span, err) }()
+
+	if sku < 1 {
+		return model.NewValidationError("sku", "sku must be greater than zero")
+	}
+
 	if userId == uuid.Nil {
-		return errors.New("user_id must be not nil")
+		return model.NewValidationError("user_id", "user_id must be not nil")
 	}
 
-	err := s.cartRepository.RemoveAllCartItemsByUserId(ctx, userId)
-	if err != nil {
-		return fmt.Errorf("cartRepository.RemoveAllCartItemsByUserId :%w", err)
+	return s.withinCartTransaction(ctx, userId, func(ctx context.Context) error {
+		removed, err := s.cartRepository.RemoveCartItem(ctx, userId, sku)
+		if err != nil {
+			return fmt.Errorf("cartRepository.RemoveProduct :%w", err)
+		}
+
+		// Удаление отсутствующего товара не меняет корзину, событие о нем не публикуется
+		if !removed {
+			return nil
+		}
+
+		return s.addEvent(ctx, model.CartEvent{
+			Type:   model.CartEventItemRemoved,
+			UserId: userId,
+			SkuId:  sku,
+		})
+	})
+}
+
+func (s *CartService) RemoveAllProducts(ctx context.Context, userId uuid.UUID) (err error) {
+	ctx, span := tracer.Start(ctx, "CartService.RemoveAllProducts", trace.WithAttributes(tracing.UserId(userId)))
+	defer func() { tracing.End(span, err) }()
+
+	if userId == uuid.Nil {
+		return model.NewValidationError("user_id", "user_id must be not nil")
 	}
 
-	return nil
+	return s.withinCartTransaction(ctx, userId, func(ctx context.Context) error {
+		removed, err := s.cartRepository.RemoveAllCartItemsByUserId(ctx, userId)
+		if err != nil {
+			return fmt.Errorf("cartRepository.RemoveAllCartItemsByUserId :%w", err)
+		}
+
+		if !removed {
+			return nil
+		}
+
+		return s.addEvent(ctx, model.CartEvent{
+			Type:   model.CartEventCartCleared,
+			UserId: userId,
+		})
+	})
 }
 
-func (s *CartService) GetItemsByUserId(ctx context.Context, userId uuid.UUID) ([]model.CartItem, error) {
+func (s *CartService) GetItemsByUserId(ctx context.Context, userId uuid.UUID) (_ []model.CartItem, err error) {
+	ctx, span := tracer.Start(ctx, "CartService.GetItemsByUserId", trace.WithAttributes(tracing.UserId(userId)))
+	defer func() { tracing.End(span, err) }()
+
 	if userId == uuid.Nil {
-		return nil, errors.New("userId must be not Nil")
+		return nil, model.NewValidationError("user_id", "userId must be not Nil")
 	}
 
 	reviews, err := s.cartRepository.GetCartItemsByUserId(ctx, userId)
@@ -123,3 +382,377 @@ func (s *CartService) GetItemsByUserId(ctx context.Context, userId uuid.UUID) ([
 
 	return reviews, nil
 }
+
+func (s *CartService) Checkout(ctx context.Context, userId uuid.UUID) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "CartService.Checkout", trace.WithAttributes(tracing.UserId(userId)))
+	defer func() { tracing.End(span, err) }()
+
+	if userId == uuid.Nil {
+		return 0, model.NewValidationError("user_id", "user_id must be not nil")
+	}
+
+	// Товары и остатки проверяются до блокировки, чтобы не держать корзину и соединение с базой
+	// на время запросов во внешние сервисы
+	cartItems, err := s.cartRepository.GetCartItemsByUserId(ctx, userId)
+	if err != nil {
+		return 0, fmt.Errorf("cartRepository.GetCartItemsByUserId :%w", err)
+	}
+
+	if len(cartItems) == 0 {
+		return 0, model.ErrCartItemsNotFound
+	}
+
+	// Не оформляем заказ, если какой-то из товаров пропал из каталога
+	_, err = s.productService.GetProductsBySkus(ctx, cartItemSkus(cartItems))
+	if err != nil {
+		return 0, fmt.Errorf("productService.GetProductsBySkus :%w", err)
+	}
+
+	availableCounts, err := s.getAvailableCounts(ctx, cartItemSkus(cartItems))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cartItem := range cartItems {
+		if available := availableCounts[cartItem.SkuId]; available < uint64(cartItem.Count) {
+			return 0, model.NewInsufficientStockError(cartItem.SkuId, available, uint64(cartItem.Count))
+		}
+	}
+
+	// Корзина заблокирована до очистки: ее нельзя изменить между созданием заказа и очисткой,
+	// а проверка If-Match атомарна с очисткой
+	var orderId int64
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		version, err := s.lockCart(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		lockedItems, err := s.cartRepository.GetCartItemsByUserId(ctx, userId)
+		if err != nil {
+			return fmt.Errorf("cartRepository.GetCartItemsByUserId :%w", err)
+		}
+
+		// Проверки выше относятся к прочитанному содержимому; если корзину успели изменить, их результат устарел
+		if !maps.Equal(cartCounts(cartItems), cartCounts(lockedItems)) {
+			return model.ErrCartChangedDuringCheckout
+		}
+
+		// Ключ зависит от версии корзины: если заказ создан, а транзакция не зафиксировалась,
+		// повтор отправит тот же ключ и LOMS вернет уже созданный заказ
+		orderId, err = s.orderService.CreateOrder(ctx, userId, lockedItems, checkoutIdempotencyKey(userId, version))
+		if err != nil {
+			return fmt.Errorf("orderService.CreateOrder :%w", err)
+		}
+
+		if _, err = s.cartRepository.RemoveAllCartItemsByUserId(ctx, userId); err != nil {
+			return fmt.Errorf("cartRepository.RemoveAllCartItemsByUserId :%w", err)
+		}
+
+		if err = s.cartRepository.SetCartPromoCode(ctx, userId, ""); err != nil {
+			return fmt.Errorf("cartRepository.SetCartPromoCode :%w", err)
+		}
+
+		return s.addEvent(ctx, model.CartEvent{
+			Type:    model.CartEventCheckedOut,
+			UserId:  userId,
+			OrderId: orderId,
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return orderId, nil
+}
+
+func (s *CartService) GetCart(ctx context.Context, userId uuid.UUID) (_ *model.Cart, err error) {
+	ctx, span := tracer.Start(ctx, "CartService.GetCart", trace.WithAttributes(tracing.UserId(userId)))
+	defer func() { tracing.End(span, err) }()
+
+	// Версия читается до позиций: если корзина изменится между запросами, ETag окажется устаревшим и
+	// следующий If-Match будет отклонен, а не принят для содержимого, которое клиент не видел
+	version, err := s.GetCartVersion(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	cartItems, err := s.GetItemsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := s.getCartLines(ctx, cartItems)
+	if err != nil {
+		return nil, err
+	}
+
+	// Недоступность склада не мешает показать корзину: у позиций без остатка он помечается неизвестным
+	availableCounts, stockErrors := s.lookupAvailableCounts(ctx, cartItemSkus(cartItems))
+	for sku, err := range stockErrors {
+		logger.FromContext(ctx).Warn("stock lookup failed", "sku", sku, "error", err)
+	}
+
+	cart := &model.Cart{
+		UserId:  userId,
+		Version: version,
+		Items:   lines,
+	}
+
+	for i := range cart.Items {
+		available, ok := availableCounts[cart.Items[i].SkuId]
+		cart.Items[i].StockUnknown = !ok
+		cart.Items[i].OutOfStock = ok && available < uint64(cart.Items[i].Count)
+		cart.Subtotal += cart.Items[i].TotalPrice
+	}
+
+	cart.PromoCode, err = s.cartRepository.GetCartPromoCode(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("cartRepository.GetCartPromoCode :%w", err)
+	}
+
+	cart.TotalPrice = cart.Subtotal
+	if cart.PromoCode == "" {
+		return cart, nil
+	}
+
+	// Промокод остается в корзине, даже если его условия перестали выполняться или его убрали из правил:
+	// скидка просто не начисляется
+	cart.Discounts, err = s.promoEngine.Apply(cart.PromoCode, cart.Items)
+	if err != nil && !errors.Is(err, model.ErrPromoCodeNotApplicable) && !errors.Is(err, model.ErrPromoCodeNotFound) {
+		return nil, fmt.Errorf("promoEngine.Apply :%w", err)
+	}
+
+	for _, discount := range cart.Discounts {
+		cart.TotalPrice -= discount.Amount
+	}
+	cart.TotalPrice = math.Round(cart.TotalPrice*100) / 100
+
+	return cart, nil
+}
+
+func (s *CartService) GetCartVersion(ctx context.Context, userId uuid.UUID) (_ uint64, err error) {
+	ctx, span := tracer.Start(ctx, "CartService.GetCartVersion", trace.WithAttributes(tracing.UserId(userId)))
+	defer func() { tracing.End(span, err) }()
+
+	if userId == uuid.Nil {
+		return 0, model.NewValidationError("user_id", "user_id must be not nil")
+	}
+
+	version, err := s.cartRepository.GetCartVersion(ctx, userId)
+	if err != nil {
+		return 0, fmt.Errorf("cartRepository.GetCartVersion :%w", err)
+	}
+
+	return version, nil
+}
+
+// withinCartTransaction выполняет изменение корзины в транзакции под блокировкой корзины,
+// предварительно проверив ожидаемую версию. Блокировка сериализует конкурентные изменения
+// одной корзины, поэтому проверки внутри fn видят актуальное содержимое
+func (s *CartService) withinCartTransaction(ctx context.Context, userId uuid.UUID, fn func(ctx context.Context) error) error {
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.lockCart(ctx, userId); err != nil {
+			return err
+		}
+
+		return fn(ctx)
+	})
+}
+
+// lockCart блокирует корзину до конца транзакции, проверяет ожидаемую версию и возвращает текущую
+func (s *CartService) lockCart(ctx context.Context, userId uuid.UUID) (uint64, error) {
+	version, err := s.cartRepository.LockCartVersion(ctx, userId)
+	if err != nil {
+		return 0, fmt.Errorf("cartRepository.LockCartVersion :%w", err)
+	}
+
+	if err = matchCartVersion(ctx, version); err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+func checkoutIdempotencyKey(userId uuid.UUID, version uint64) string {
+	return fmt.Sprintf("cart-checkout:%s:%d", userId, version)
+}
+
+func matchCartVersion(ctx context.Context, version uint64) error {
+	expectedVersions, ok := model.ExpectedCartVersionsFromContext(ctx)
+	if ok && !slices.Contains(expectedVersions, version) {
+		return model.NewCartVersionMismatchError(version)
+	}
+
+	return nil
+}
+
+// checkCartLimits проверяет, что корзина не нарушит лимиты, если количество sku станет равным newCount от текущего.
+// Должна вызываться внутри withinCartTransaction, чтобы содержимое корзины не изменилось до записи
+func (s *CartService) checkCartLimits(ctx context.Context, userId uuid.UUID, sku uint64, newCount func(current uint64) uint64) error {
+	cartItems, err := s.cartRepository.GetCartItemsByUserId(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("cartRepository.GetCartItemsByUserId :%w", err)
+	}
+
+	counts := cartCounts(cartItems)
+
+	return s.checkLimits(counts, sku, newCount(counts[sku]))
+}
+
+// checkLimits проверяет, что корзина с количествами counts не нарушит лимиты, если количество sku станет равным count
+func (s *CartService) checkLimits(counts map[uint64]uint64, sku uint64, count uint64) error {
+	var total uint64
+	for _, itemCount := range counts {
+		total += itemCount
+	}
+
+	distinct := len(counts)
+	current := counts[sku]
+
+	// Лимиты могли уменьшить после наполнения корзины, поэтому отклоняются только изменения,
+	// которые увеличивают показатель сверх лимита: уменьшать такую корзину можно
+	if count > current && count > s.limits.UnitsPerSku() {
+		return model.NewCartLimitExceededError(model.CartLimitUnitsPerSku, s.limits.UnitsPerSku(), count)
+	}
+
+	newDistinct := distinct
+	if current == 0 && count > 0 {
+		newDistinct++
+	}
+
+	if s.limits.MaxDistinctSkus > 0 && newDistinct > distinct && newDistinct > s.limits.MaxDistinctSkus {
+		return model.NewCartLimitExceededError(model.CartLimitDistinctSkus, uint64(s.limits.MaxDistinctSkus), uint64(newDistinct))
+	}
+
+	newTotal := total - current + count
+	if s.limits.MaxTotalUnits > 0 && newTotal > total && newTotal > s.limits.MaxTotalUnits {
+		return model.NewCartLimitExceededError(model.CartLimitTotalUnits, s.limits.MaxTotalUnits, newTotal)
+	}
+
+	return nil
+}
+
+func cartCounts(cartItems []model.CartItem) map[uint64]uint64 {
+	counts := make(map[uint64]uint64, len(cartItems))
+	for _, cartItem := range cartItems {
+		counts[cartItem.SkuId] = uint64(cartItem.Count)
+	}
+
+	return counts
+}
+
+// checkStock проверяет, что товара sku на складе не меньше requested
+func (s *CartService) checkStock(ctx context.Context, sku uint64, requested uint64) error {
+	available, err := s.stockService.GetAvailableCount(ctx, sku)
+	if err != nil {
+		return fmt.Errorf("stockService.GetAvailableCount :%w", err)
+	}
+
+	if available < requested {
+		return model.NewInsufficientStockError(sku, available, requested)
+	}
+
+	return nil
+}
+
+// getAvailableCounts запрашивает остатки товаров с ограниченной параллельностью.
+// Если хотя бы один остаток узнать не удалось, возвращается ошибка первого такого товара.
+func (s *CartService) getAvailableCounts(ctx context.Context, skus []uint64) (map[uint64]uint64, error) {
+	counts, lookupErrors := s.lookupAvailableCounts(ctx, skus)
+	for _, sku := range skus {
+		if err, ok := lookupErrors[sku]; ok {
+			return nil, err
+		}
+	}
+
+	return counts, nil
+}
+
+// lookupAvailableCounts запрашивает остатки товаров с ограниченной параллельностью
+// и возвращает их вместе с ошибками по товарам, остаток которых узнать не удалось
+func (s *CartService) lookupAvailableCounts(ctx context.Context, skus []uint64) (map[uint64]uint64, map[uint64]error) {
+	var (
+		mu           sync.Mutex
+		counts       = make(map[uint64]uint64, len(skus))
+		lookupErrors = make(map[uint64]error)
+		wg           sync.WaitGroup
+		semaphore    = make(chan struct{}, stockLookupConcurrency)
+	)
+
+	for _, sku := range skus {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			available, err := s.stockService.GetAvailableCount(ctx, sku)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				lookupErrors[sku] = fmt.Errorf("stockService.GetAvailableCount :%w", err)
+				return
+			}
+
+			counts[sku] = available
+		}()
+	}
+
+	wg.Wait()
+
+	return counts, lookupErrors
+}
+
+// getCartLines дополняет позиции корзины данными товаров и упорядочивает их по sku
+func (s *CartService) getCartLines(ctx context.Context, cartItems []model.CartItem) ([]model.CartLine, error) {
+	sort.Slice(cartItems, func(i, j int) bool {
+		return cartItems[i].SkuId < cartItems[j].SkuId
+	})
+
+	// Товары, которые не удалось получить, остаются в корзине без имени и цены
+	products, err := s.productService.GetProductsBySkus(ctx, cartItemSkus(cartItems))
+	var lookupErr *model.ProductsLookupError
+	if err != nil && !errors.As(err, &lookupErr) {
+		return nil, fmt.Errorf("productService.GetProductsBySkus :%w", err)
+	}
+
+	lines := make([]model.CartLine, 0, len(cartItems))
+	for _, cartItem := range cartItems {
+		product, ok := products[cartItem.SkuId]
+		if !ok || product == nil {
+			lines = append(lines, model.CartLine{CartItem: cartItem, ProductUnavailable: true})
+			continue
+		}
+
+		lines = append(lines, model.CartLine{
+			CartItem:   cartItem,
+			Name:       product.Name,
+			Price:      product.Price,
+			TotalPrice: product.Price * float64(cartItem.Count),
+		})
+	}
+
+	return lines, nil
+}
+
+func cartItemSkus(cartItems []model.CartItem) []uint64 {
+	skus := make([]uint64, 0, len(cartItems))
+	for _, cartItem := range cartItems {
+		skus = append(skus, cartItem.SkuId)
+	}
+
+	return skus
+}
+
+func (s *CartService) addEvent(ctx context.Context, event model.CartEvent) error {
+	event.OccurredAt = time.Now().UTC()
+
+	if err := s.outboxRepository.AddCartEvent(ctx, event); err != nil {
+		return fmt.Errorf("outboxRepository.AddCartEvent :%w", err)
+	}
+
+	return nil
+}
@@ -2,27 +2,176 @@ package service
 
 import (
 	"context"
+	"math"
+
 	"github.com/google/uuid"
 	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
 )
 
 type stubCartRepo struct {
-	addFn func(ctx context.Context, item model.CartItem) error
-	getFn func(ctx context.Context, userId uuid.UUID) ([]model.CartItem, error)
+	addFn       func(ctx context.Context, item model.CartItem) error
+	upsertFn    func(ctx context.Context, item model.CartItem) (*model.CartItem, error)
+	updateFn    func(ctx context.Context, id uint64, item model.CartItem) error
+	getFn       func(ctx context.Context, userId uuid.UUID) ([]model.CartItem, error)
+	getItemFn   func(ctx context.Context, userId uuid.UUID, sku uint64) (*model.CartItem, error)
+	removeFn    func(ctx context.Context, userId uuid.UUID, sku uint64) (bool, error)
+	removeAllFn func(ctx context.Context, userId uuid.UUID) (bool, error)
+	versionFn   func(ctx context.Context, userId uuid.UUID) (uint64, error)
+	setPromoFn  func(ctx context.Context, userId uuid.UUID, code string) error
+	getPromoFn  func(ctx context.Context, userId uuid.UUID) (string, error)
+}
+
+func (s *stubCartRepo) AddCartItem(ctx context.Context, item model.CartItem) (*model.CartItem, error) {
+	if err := s.addFn(ctx, item); err != nil {
+		return nil, err
+	}
+
+	return &item, nil
 }
 
-func (s *stubCartRepo) AddCartItem(ctx context.Context, item model.CartItem) error {
-	return s.addFn(ctx, item)
+func (s *stubCartRepo) UpsertCartItem(ctx context.Context, item model.CartItem) (*model.CartItem, error) {
+	return s.upsertFn(ctx, item)
+}
+
+func (s *stubCartRepo) UpdateCartItem(ctx context.Context, id uint64, item model.CartItem) (*model.CartItem, error) {
+	if err := s.updateFn(ctx, id, item); err != nil {
+		return nil, err
+	}
+
+	item.Id = id
+
+	return &item, nil
 }
 
 func (s *stubCartRepo) GetCartItemsByUserId(ctx context.Context, userId uuid.UUID) ([]model.CartItem, error) {
+	if s.getFn == nil {
+		return nil, nil
+	}
+
 	return s.getFn(ctx, userId)
 }
 
+func (s *stubCartRepo) GetCartItem(ctx context.Context, userId uuid.UUID, sku uint64) (*model.CartItem, error) {
+	if s.getItemFn == nil {
+		return nil, model.ErrCartItemsNotFound
+	}
+
+	return s.getItemFn(ctx, userId, sku)
+}
+
+func (s *stubCartRepo) RemoveCartItem(ctx context.Context, userId uuid.UUID, sku uint64) (bool, error) {
+	return s.removeFn(ctx, userId, sku)
+}
+
+func (s *stubCartRepo) RemoveAllCartItemsByUserId(ctx context.Context, userId uuid.UUID) (bool, error) {
+	return s.removeAllFn(ctx, userId)
+}
+
+func (s *stubCartRepo) GetCartVersion(ctx context.Context, userId uuid.UUID) (uint64, error) {
+	if s.versionFn == nil {
+		return 0, nil
+	}
+
+	return s.versionFn(ctx, userId)
+}
+
+func (s *stubCartRepo) LockCartVersion(ctx context.Context, userId uuid.UUID) (uint64, error) {
+	return s.GetCartVersion(ctx, userId)
+}
+
+func (s *stubCartRepo) SetCartPromoCode(ctx context.Context, userId uuid.UUID, code string) error {
+	if s.setPromoFn == nil {
+		return nil
+	}
+
+	return s.setPromoFn(ctx, userId, code)
+}
+
+func (s *stubCartRepo) GetCartPromoCode(ctx context.Context, userId uuid.UUID) (string, error) {
+	if s.getPromoFn == nil {
+		return "", nil
+	}
+
+	return s.getPromoFn(ctx, userId)
+}
+
 type stubProductService struct {
-	getFn func(ctx context.Context, sku uint64) (*model.Product, error)
+	getFn      func(ctx context.Context, sku uint64) (*model.Product, error)
+	getBatchFn func(ctx context.Context, skus []uint64) (map[uint64]*model.Product, error)
 }
 
 func (s *stubProductService) GetProductBySku(ctx context.Context, sku uint64) (*model.Product, error) {
 	return s.getFn(ctx, sku)
 }
+
+// GetProductsBySkus по умолчанию опрашивает getFn по каждому sku
+func (s *stubProductService) GetProductsBySkus(ctx context.Context, skus []uint64) (map[uint64]*model.Product, error) {
+	if s.getBatchFn != nil {
+		return s.getBatchFn(ctx, skus)
+	}
+
+	products := make(map[uint64]*model.Product, len(skus))
+	lookupErrors := make(map[uint64]error)
+	for _, sku := range skus {
+		product, err := s.getFn(ctx, sku)
+		if err != nil {
+			lookupErrors[sku] = err
+			continue
+		}
+
+		products[sku] = product
+	}
+
+	if len(lookupErrors) > 0 {
+		return products, &model.ProductsLookupError{Errors: lookupErrors}
+	}
+
+	return products, nil
+}
+
+type stubStockService struct {
+	countFn func(ctx context.Context, sku uint64) (uint64, error)
+}
+
+func (s *stubStockService) GetAvailableCount(ctx context.Context, sku uint64) (uint64, error) {
+	if s.countFn == nil {
+		return math.MaxUint64, nil
+	}
+
+	return s.countFn(ctx, sku)
+}
+
+type stubPromoEngine struct {
+	applyFn func(code string, lines []model.CartLine) ([]model.CartDiscount, error)
+}
+
+func (s *stubPromoEngine) Apply(code string, lines []model.CartLine) ([]model.CartDiscount, error) {
+	return s.applyFn(code, lines)
+}
+
+type stubOrderService struct {
+	createFn func(ctx context.Context, userId uuid.UUID, items []model.CartItem) (int64, error)
+	keys     []string
+}
+
+func (s *stubOrderService) CreateOrder(ctx context.Context, userId uuid.UUID, items []model.CartItem, idempotencyKey string) (int64, error) {
+	s.keys = append(s.keys, idempotencyKey)
+
+	return s.createFn(ctx, userId, items)
+}
+
+type stubOutboxRepo struct {
+	events []model.CartEvent
+}
+
+func (s *stubOutboxRepo) AddCartEvent(_ context.Context, event model.CartEvent) error {
+	s.events = append(s.events, event)
+
+	return nil
+}
+
+type stubTransactor struct{}
+
+func (s *stubTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -3,22 +3,27 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/tracing"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/tracing/tracingtest"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
 )
 
 func TestCartService_AddProduct_OK(t *testing.T) {
 	userId := uuid.New()
 
 	cartRepo := &stubCartRepo{
-		addFn: func(ctx context.Context, item model.CartItem) error {
+		upsertFn: func(ctx context.Context, item model.CartItem) (*model.CartItem, error) {
 			require.Equal(t, userId, item.UserId)
 			require.Equal(t, uint64(10), item.SkuId)
 			require.Equal(t, uint32(3), item.Count)
-			return nil
+			return &item, nil
 		},
 	}
 
@@ -28,14 +33,14 @@ func TestCartService_AddProduct_OK(t *testing.T) {
 		},
 	}
 
-	svc := NewCartService(cartRepo, productSrv)
+	svc := NewCartService(cartRepo, productSrv, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
 
 	err := svc.AddProduct(context.Background(), userId, 10, 3)
 	require.NoError(t, err)
 }
 
 func TestCartService_AddProduct_InvalidSku(t *testing.T) {
-	svc := NewCartService(nil, nil)
+	svc := NewCartService(nil, nil, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
 
 	err := svc.AddProduct(context.Background(), uuid.New(), 0, 1)
 	require.Error(t, err)
@@ -43,7 +48,7 @@ func TestCartService_AddProduct_InvalidSku(t *testing.T) {
 }
 
 func TestCartService_AddProduct_InvalidUserId(t *testing.T) {
-	svc := NewCartService(nil, nil)
+	svc := NewCartService(nil, nil, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
 
 	err := svc.AddProduct(context.Background(), uuid.Nil, 10, 1)
 	require.Error(t, err)
@@ -61,7 +66,7 @@ func TestCartService_AddProduct_ProductNotFound(t *testing.T) {
 
 	cartRepo := &stubCartRepo{}
 
-	svc := NewCartService(cartRepo, productSrv)
+	svc := NewCartService(cartRepo, productSrv, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
 
 	err := svc.AddProduct(context.Background(), userId, 10, 1)
 	require.Error(t, err)
@@ -78,12 +83,12 @@ func TestCartService_AddProduct_RepoError(t *testing.T) {
 	}
 
 	cartRepo := &stubCartRepo{
-		addFn: func(ctx context.Context, item model.CartItem) error {
-			return errors.New("db failure")
+		upsertFn: func(ctx context.Context, item model.CartItem) (*model.CartItem, error) {
+			return nil, errors.New("db failure")
 		},
 	}
 
-	svc := NewCartService(cartRepo, productSrv)
+	svc := NewCartService(cartRepo, productSrv, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
 
 	err := svc.AddProduct(context.Background(), userId, 10, 1)
 	require.Error(t, err)
@@ -104,7 +109,7 @@ func TestCartService_GetItemsByUserId_OK(t *testing.T) {
 		},
 	}
 
-	svc := NewCartService(cartRepo, nil)
+	svc := NewCartService(cartRepo, nil, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
 
 	items, err := svc.GetItemsByUserId(context.Background(), userId)
 	require.NoError(t, err)
@@ -112,7 +117,7 @@ func TestCartService_GetItemsByUserId_OK(t *testing.T) {
 }
 
 func TestCartService_GetItemsByUserId_InvalidUser(t *testing.T) {
-	svc := NewCartService(nil, nil)
+	svc := NewCartService(nil, nil, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
 
 	items, err := svc.GetItemsByUserId(context.Background(), uuid.Nil)
 	require.Error(t, err)
@@ -128,10 +133,975 @@ func TestCartService_GetItemsByUserId_RepoError(t *testing.T) {
 		},
 	}
 
-	svc := NewCartService(cartRepo, nil)
+	svc := NewCartService(cartRepo, nil, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
 
 	items, err := svc.GetItemsByUserId(context.Background(), userId)
 	require.Error(t, err)
 	require.Nil(t, items)
 	require.Contains(t, err.Error(), "cartRepository")
 }
+
+func TestCartService_Checkout_OK(t *testing.T) {
+	userId := uuid.New()
+
+	items := []model.CartItem{
+		{UserId: userId, SkuId: 1, Count: 2},
+		{UserId: userId, SkuId: 2, Count: 1},
+	}
+
+	removed := false
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			return items, nil
+		},
+		removeAllFn: func(ctx context.Context, uid uuid.UUID) (bool, error) {
+			require.Equal(t, userId, uid)
+			removed = true
+			return true, nil
+		},
+	}
+
+	orderSrv := &stubOrderService{
+		createFn: func(ctx context.Context, uid uuid.UUID, orderItems []model.CartItem) (int64, error) {
+			require.Equal(t, userId, uid)
+			require.Equal(t, items, orderItems)
+			return 42, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, existingProducts(), &stubStockService{}, orderSrv, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	orderId, err := svc.Checkout(context.Background(), userId)
+	require.NoError(t, err)
+	require.Equal(t, int64(42), orderId)
+	require.True(t, removed)
+}
+
+func TestCartService_Checkout_EmptyCart(t *testing.T) {
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			return nil, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, nil, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	_, err := svc.Checkout(context.Background(), uuid.New())
+	require.ErrorIs(t, err, model.ErrCartItemsNotFound)
+}
+
+func TestCartService_Checkout_OrderFailedKeepsCart(t *testing.T) {
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			return []model.CartItem{{UserId: uid, SkuId: 1, Count: 1}}, nil
+		},
+		removeAllFn: func(ctx context.Context, uid uuid.UUID) (bool, error) {
+			t.Fatal("cart must not be cleared when order creation failed")
+			return true, nil
+		},
+	}
+
+	orderSrv := &stubOrderService{
+		createFn: func(ctx context.Context, uid uuid.UUID, orderItems []model.CartItem) (int64, error) {
+			return 0, errors.New("loms unavailable")
+		},
+	}
+
+	svc := NewCartService(cartRepo, existingProducts(), &stubStockService{}, orderSrv, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	_, err := svc.Checkout(context.Background(), uuid.New())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "orderService")
+}
+
+func TestCartService_GetCart_OK(t *testing.T) {
+	userId := uuid.New()
+
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			return []model.CartItem{
+				{UserId: userId, SkuId: 2, Count: 3},
+				{UserId: userId, SkuId: 1, Count: 2},
+			}, nil
+		},
+	}
+
+	productSrv := &stubProductService{
+		getFn: func(ctx context.Context, sku uint64) (*model.Product, error) {
+			return &model.Product{Sku: sku, Name: fmt.Sprintf("product %d", sku), Price: float64(sku) * 10}, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, productSrv, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	cart, err := svc.GetCart(context.Background(), userId)
+	require.NoError(t, err)
+	require.Len(t, cart.Items, 2)
+	require.Equal(t, uint64(1), cart.Items[0].SkuId)
+	require.Equal(t, "product 1", cart.Items[0].Name)
+	require.Equal(t, 10.0, cart.Items[0].Price)
+	require.Equal(t, 20.0, cart.Items[0].TotalPrice)
+	require.Equal(t, 60.0, cart.Items[1].TotalPrice)
+	require.Equal(t, 80.0, cart.TotalPrice)
+}
+
+func TestCartService_GetCart_ProductNotFound(t *testing.T) {
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			return []model.CartItem{{UserId: uid, SkuId: 1, Count: 1}, {UserId: uid, SkuId: 2, Count: 2}}, nil
+		},
+	}
+
+	productSrv := &stubProductService{
+		getFn: func(ctx context.Context, sku uint64) (*model.Product, error) {
+			if sku == 1 {
+				return nil, model.ErrProductNotFound
+			}
+			return &model.Product{Sku: sku, Name: "product", Price: 5}, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, productSrv, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	// Пропавший из каталога товар остается в корзине без цены, остальные позиции отдаются как обычно
+	cart, err := svc.GetCart(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.Len(t, cart.Items, 2)
+	require.True(t, cart.Items[0].ProductUnavailable)
+	require.Zero(t, cart.Items[0].TotalPrice)
+	require.False(t, cart.Items[1].ProductUnavailable)
+	require.Equal(t, 10.0, cart.TotalPrice)
+}
+
+func TestCartService_GetCart_ProductServiceUnavailable(t *testing.T) {
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			return []model.CartItem{{UserId: uid, SkuId: 1, Count: 1}}, nil
+		},
+	}
+
+	productSrv := &stubProductService{
+		getBatchFn: func(ctx context.Context, skus []uint64) (map[uint64]*model.Product, error) {
+			return nil, model.ErrProductServiceUnavailable
+		},
+	}
+
+	svc := NewCartService(cartRepo, productSrv, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	cart, err := svc.GetCart(context.Background(), uuid.New())
+	require.ErrorIs(t, err, model.ErrProductServiceUnavailable)
+	require.Nil(t, cart)
+}
+
+func TestCartService_AddProduct_WritesOutboxEvents(t *testing.T) {
+	userId := uuid.New()
+
+	var existing *model.CartItem
+	cartRepo := &stubCartRepo{
+		getItemFn: func(ctx context.Context, uid uuid.UUID, sku uint64) (*model.CartItem, error) {
+			if existing == nil {
+				return nil, model.ErrCartItemsNotFound
+			}
+			return existing, nil
+		},
+		upsertFn: func(ctx context.Context, item model.CartItem) (*model.CartItem, error) {
+			if existing == nil {
+				existing = &model.CartItem{Id: 1, UserId: item.UserId, SkuId: item.SkuId}
+			}
+			existing.Count += item.Count
+			result := *existing
+			return &result, nil
+		},
+	}
+
+	productCalls := 0
+	productSrv := &stubProductService{
+		getFn: func(ctx context.Context, sku uint64) (*model.Product, error) {
+			productCalls++
+			return &model.Product{Sku: sku}, nil
+		},
+	}
+
+	outboxRepo := &stubOutboxRepo{}
+	svc := NewCartService(cartRepo, productSrv, &stubStockService{}, nil, nil, outboxRepo, &stubTransactor{}, model.CartLimits{})
+
+	require.NoError(t, svc.AddProduct(context.Background(), userId, 10, 2))
+	require.NoError(t, svc.AddProduct(context.Background(), userId, 10, 3))
+
+	require.Equal(t, 1, productCalls)
+	require.Len(t, outboxRepo.events, 2)
+	require.Equal(t, model.CartEventItemAdded, outboxRepo.events[0].Type)
+	require.Equal(t, uint32(2), outboxRepo.events[0].Count)
+	require.Equal(t, model.CartEventItemCountChanged, outboxRepo.events[1].Type)
+	require.Equal(t, uint32(5), outboxRepo.events[1].Count)
+	require.Equal(t, userId, outboxRepo.events[1].UserId)
+}
+
+func TestCartService_Checkout_WritesOutboxEvent(t *testing.T) {
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			return []model.CartItem{{UserId: uid, SkuId: 1, Count: 1}}, nil
+		},
+		removeAllFn: func(ctx context.Context, uid uuid.UUID) (bool, error) {
+			return true, nil
+		},
+	}
+
+	orderSrv := &stubOrderService{
+		createFn: func(ctx context.Context, uid uuid.UUID, orderItems []model.CartItem) (int64, error) {
+			return 7, nil
+		},
+	}
+
+	outboxRepo := &stubOutboxRepo{}
+	svc := NewCartService(cartRepo, existingProducts(), &stubStockService{}, orderSrv, nil, outboxRepo, &stubTransactor{}, model.CartLimits{})
+
+	_, err := svc.Checkout(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.Len(t, outboxRepo.events, 1)
+	require.Equal(t, model.CartEventCheckedOut, outboxRepo.events[0].Type)
+	require.Equal(t, int64(7), outboxRepo.events[0].OrderId)
+}
+
+func TestCartService_SetProductCount_UpdatesExisting(t *testing.T) {
+	userId := uuid.New()
+
+	cartRepo := &stubCartRepo{
+		getItemFn: func(ctx context.Context, uid uuid.UUID, sku uint64) (*model.CartItem, error) {
+			return &model.CartItem{Id: 5, UserId: uid, SkuId: sku, Count: 5}, nil
+		},
+		updateFn: func(ctx context.Context, id uint64, item model.CartItem) error {
+			require.Equal(t, uint64(5), id)
+			require.Equal(t, uint32(3), item.Count)
+			return nil
+		},
+	}
+
+	outboxRepo := &stubOutboxRepo{}
+	svc := NewCartService(cartRepo, nil, &stubStockService{}, nil, nil, outboxRepo, &stubTransactor{}, model.CartLimits{})
+
+	require.NoError(t, svc.SetProductCount(context.Background(), userId, 10, 3))
+	require.Len(t, outboxRepo.events, 1)
+	require.Equal(t, model.CartEventItemCountChanged, outboxRepo.events[0].Type)
+}
+
+func TestCartService_SetProductCount_AddsNew(t *testing.T) {
+	userId := uuid.New()
+
+	added := false
+	cartRepo := &stubCartRepo{
+		addFn: func(ctx context.Context, item model.CartItem) error {
+			require.Equal(t, uint32(4), item.Count)
+			added = true
+			return nil
+		},
+	}
+
+	productSrv := &stubProductService{
+		getFn: func(ctx context.Context, sku uint64) (*model.Product, error) {
+			return &model.Product{Sku: sku}, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, productSrv, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	require.NoError(t, svc.SetProductCount(context.Background(), userId, 10, 4))
+	require.True(t, added)
+}
+
+func TestCartService_SetProductCount_ProductNotFound(t *testing.T) {
+	productSrv := &stubProductService{
+		getFn: func(ctx context.Context, sku uint64) (*model.Product, error) {
+			return nil, model.ErrProductNotFound
+		},
+	}
+
+	svc := NewCartService(&stubCartRepo{}, productSrv, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	err := svc.SetProductCount(context.Background(), uuid.New(), 10, 4)
+	require.ErrorIs(t, err, model.ErrProductNotFound)
+}
+
+func TestCartService_SetProductCount_ZeroRemoves(t *testing.T) {
+	removed := false
+	cartRepo := &stubCartRepo{
+		removeFn: func(ctx context.Context, uid uuid.UUID, sku uint64) (bool, error) {
+			require.Equal(t, uint64(10), sku)
+			removed = true
+			return true, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, nil, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	require.NoError(t, svc.SetProductCount(context.Background(), uuid.New(), 10, 0))
+	require.True(t, removed)
+}
+
+func TestCartService_DecreaseProductCount_Updates(t *testing.T) {
+	cartRepo := &stubCartRepo{
+		getItemFn: func(ctx context.Context, uid uuid.UUID, sku uint64) (*model.CartItem, error) {
+			return &model.CartItem{Id: 5, UserId: uid, SkuId: sku, Count: 5}, nil
+		},
+		updateFn: func(ctx context.Context, id uint64, item model.CartItem) error {
+			require.Equal(t, uint32(3), item.Count)
+			return nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, nil, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	require.NoError(t, svc.DecreaseProductCount(context.Background(), uuid.New(), 10, 2))
+}
+
+func TestCartService_DecreaseProductCount_ToZeroRemoves(t *testing.T) {
+	removed := false
+	cartRepo := &stubCartRepo{
+		getItemFn: func(ctx context.Context, uid uuid.UUID, sku uint64) (*model.CartItem, error) {
+			return &model.CartItem{Id: 5, UserId: uid, SkuId: sku, Count: 2}, nil
+		},
+		removeFn: func(ctx context.Context, uid uuid.UUID, sku uint64) (bool, error) {
+			removed = true
+			return true, nil
+		},
+	}
+
+	outboxRepo := &stubOutboxRepo{}
+	svc := NewCartService(cartRepo, nil, &stubStockService{}, nil, nil, outboxRepo, &stubTransactor{}, model.CartLimits{})
+
+	require.NoError(t, svc.DecreaseProductCount(context.Background(), uuid.New(), 10, 2))
+	require.True(t, removed)
+	require.Equal(t, model.CartEventItemRemoved, outboxRepo.events[0].Type)
+}
+
+func TestCartService_DecreaseProductCount_NotFound(t *testing.T) {
+	svc := NewCartService(&stubCartRepo{}, nil, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	err := svc.DecreaseProductCount(context.Background(), uuid.New(), 10, 1)
+	require.ErrorIs(t, err, model.ErrCartItemsNotFound)
+}
+
+func TestCartService_RemoveProduct_MissingItemWritesNoEvent(t *testing.T) {
+	cartRepo := &stubCartRepo{
+		removeFn: func(ctx context.Context, uid uuid.UUID, sku uint64) (bool, error) {
+			return false, nil
+		},
+	}
+
+	outboxRepo := &stubOutboxRepo{}
+	svc := NewCartService(cartRepo, nil, &stubStockService{}, nil, nil, outboxRepo, &stubTransactor{}, model.CartLimits{})
+
+	require.NoError(t, svc.RemoveProduct(context.Background(), uuid.New(), 10))
+	require.Empty(t, outboxRepo.events)
+}
+
+func TestCartService_RemoveAllProducts_EmptyCartWritesNoEvent(t *testing.T) {
+	cartRepo := &stubCartRepo{
+		removeAllFn: func(ctx context.Context, uid uuid.UUID) (bool, error) {
+			return false, nil
+		},
+	}
+
+	outboxRepo := &stubOutboxRepo{}
+	svc := NewCartService(cartRepo, nil, &stubStockService{}, nil, nil, outboxRepo, &stubTransactor{}, model.CartLimits{})
+
+	require.NoError(t, svc.RemoveAllProducts(context.Background(), uuid.New()))
+	require.Empty(t, outboxRepo.events)
+}
+
+func existingProducts() *stubProductService {
+	return &stubProductService{
+		getFn: func(ctx context.Context, sku uint64) (*model.Product, error) {
+			return &model.Product{Sku: sku}, nil
+		},
+	}
+}
+
+func TestCartService_Checkout_ProductGone(t *testing.T) {
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			return []model.CartItem{{UserId: uid, SkuId: 1, Count: 1}, {UserId: uid, SkuId: 2, Count: 1}}, nil
+		},
+	}
+
+	productSrv := &stubProductService{
+		getBatchFn: func(ctx context.Context, skus []uint64) (map[uint64]*model.Product, error) {
+			require.ElementsMatch(t, []uint64{1, 2}, skus)
+			return map[uint64]*model.Product{1: {Sku: 1}}, &model.ProductsLookupError{
+				Errors: map[uint64]error{2: model.ErrProductNotFound},
+			}
+		},
+	}
+
+	orderSrv := &stubOrderService{
+		createFn: func(ctx context.Context, uid uuid.UUID, orderItems []model.CartItem) (int64, error) {
+			t.Fatal("order must not be created when a product is missing")
+			return 0, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, productSrv, &stubStockService{}, orderSrv, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	_, err := svc.Checkout(context.Background(), uuid.New())
+	require.ErrorIs(t, err, model.ErrProductNotFound)
+}
+
+func TestCartService_AddProduct_ValidationErrorKind(t *testing.T) {
+	svc := NewCartService(&stubCartRepo{}, nil, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	err := svc.AddProduct(context.Background(), uuid.New(), 0, 1)
+
+	var domainErr *model.Error
+	require.ErrorAs(t, err, &domainErr)
+	require.Equal(t, model.ErrorKindValidation, domainErr.Kind)
+	require.Equal(t, "sku", domainErr.Details["field"])
+}
+
+func TestCartService_SpanTree(t *testing.T) {
+	tracingtest.Install()
+
+	cartRepo := &stubCartRepo{
+		removeFn: func(ctx context.Context, uid uuid.UUID, sku uint64) (bool, error) {
+			return true, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, nil, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	ctx, parent := tracer.Start(context.Background(), "request")
+	require.NoError(t, svc.SetProductCount(ctx, uuid.New(), 10, 0))
+	parent.End()
+
+	traceId := parent.SpanContext().TraceID()
+
+	setSpan := tracingtest.SpanByName(traceId, "CartService.SetProductCount")
+	require.NotNil(t, setSpan)
+	require.Equal(t, parent.SpanContext().SpanID(), setSpan.Parent().SpanID())
+	require.Contains(t, setSpan.Attributes(), tracing.Sku(10))
+
+	removeSpan := tracingtest.SpanByName(traceId, "CartService.RemoveProduct")
+	require.NotNil(t, removeSpan)
+	require.Equal(t, setSpan.SpanContext().SpanID(), removeSpan.Parent().SpanID())
+
+	// Ошибка метода сервиса отмечается в спане
+	ctx, parent = tracer.Start(context.Background(), "request")
+	_, err := svc.Checkout(ctx, uuid.Nil)
+	require.Error(t, err)
+	parent.End()
+
+	checkoutSpan := tracingtest.SpanByName(parent.SpanContext().TraceID(), "CartService.Checkout")
+	require.NotNil(t, checkoutSpan)
+	require.Equal(t, codes.Error, checkoutSpan.Status().Code)
+}
+
+func TestCartService_SetProductCount_VersionMismatch(t *testing.T) {
+	userId := uuid.New()
+
+	cartRepo := &stubCartRepo{
+		getItemFn: func(ctx context.Context, uid uuid.UUID, sku uint64) (*model.CartItem, error) {
+			return &model.CartItem{Id: 5, UserId: uid, SkuId: sku, Count: 5}, nil
+		},
+		updateFn: func(ctx context.Context, id uint64, item model.CartItem) error {
+			t.Fatal("cart must not be updated")
+			return nil
+		},
+		versionFn: func(ctx context.Context, uid uuid.UUID) (uint64, error) {
+			return 4, nil
+		},
+	}
+
+	outboxRepo := &stubOutboxRepo{}
+	svc := NewCartService(cartRepo, nil, &stubStockService{}, nil, nil, outboxRepo, &stubTransactor{}, model.CartLimits{})
+
+	ctx := model.WithExpectedCartVersions(context.Background(), []uint64{3})
+	err := svc.SetProductCount(ctx, userId, 10, 3)
+	require.ErrorIs(t, err, model.ErrCartVersionMismatch)
+	require.Empty(t, outboxRepo.events)
+
+	var domainErr *model.Error
+	require.ErrorAs(t, err, &domainErr)
+	require.Equal(t, uint64(4), domainErr.Details["version"])
+}
+
+func TestCartService_SetProductCount_VersionMatches(t *testing.T) {
+	userId := uuid.New()
+
+	updated := false
+	cartRepo := &stubCartRepo{
+		getItemFn: func(ctx context.Context, uid uuid.UUID, sku uint64) (*model.CartItem, error) {
+			return &model.CartItem{Id: 5, UserId: uid, SkuId: sku, Count: 5}, nil
+		},
+		updateFn: func(ctx context.Context, id uint64, item model.CartItem) error {
+			updated = true
+			return nil
+		},
+		versionFn: func(ctx context.Context, uid uuid.UUID) (uint64, error) {
+			return 4, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, nil, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	ctx := model.WithExpectedCartVersions(context.Background(), []uint64{3, 4})
+	require.NoError(t, svc.SetProductCount(ctx, userId, 10, 3))
+	require.True(t, updated)
+}
+
+func newLimitsCartRepo(t *testing.T, items []model.CartItem) *stubCartRepo {
+	return &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			return items, nil
+		},
+		getItemFn: func(ctx context.Context, uid uuid.UUID, sku uint64) (*model.CartItem, error) {
+			for _, item := range items {
+				if item.SkuId == sku {
+					return &item, nil
+				}
+			}
+			return nil, model.ErrCartItemsNotFound
+		},
+		upsertFn: func(ctx context.Context, item model.CartItem) (*model.CartItem, error) {
+			t.Fatal("cart must not be changed")
+			return nil, nil
+		},
+		addFn: func(ctx context.Context, item model.CartItem) error {
+			t.Fatal("cart must not be changed")
+			return nil
+		},
+		updateFn: func(ctx context.Context, id uint64, item model.CartItem) error {
+			t.Fatal("cart must not be changed")
+			return nil
+		},
+	}
+}
+
+func TestCartService_AddProduct_LimitExceeded(t *testing.T) {
+	items := []model.CartItem{
+		{Id: 1, SkuId: 10, Count: 8},
+		{Id: 2, SkuId: 20, Count: 5},
+	}
+	limits := model.CartLimits{MaxUnitsPerSku: 10, MaxDistinctSkus: 2, MaxTotalUnits: 15}
+
+	tests := []struct {
+		name      string
+		sku       uint64
+		count     uint32
+		limit     model.CartLimit
+		max       uint64
+		requested uint64
+	}{
+		{name: "units per sku", sku: 10, count: 3, limit: model.CartLimitUnitsPerSku, max: 10, requested: 11},
+		{name: "distinct skus", sku: 30, count: 1, limit: model.CartLimitDistinctSkus, max: 2, requested: 3},
+		{name: "total units", sku: 20, count: 3, limit: model.CartLimitTotalUnits, max: 15, requested: 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outboxRepo := &stubOutboxRepo{}
+			svc := NewCartService(newLimitsCartRepo(t, items), newBatchProductService(), &stubStockService{}, nil, nil, outboxRepo, &stubTransactor{}, limits)
+
+			err := svc.AddProduct(context.Background(), uuid.New(), tt.sku, tt.count)
+			require.ErrorIs(t, err, model.ErrCartLimitExceeded)
+			require.Empty(t, outboxRepo.events)
+
+			var domainErr *model.Error
+			require.ErrorAs(t, err, &domainErr)
+			require.Equal(t, model.ErrorKindUnprocessable, domainErr.Kind)
+			require.Equal(t, string(tt.limit), domainErr.Details["limit"])
+			require.Equal(t, tt.max, domainErr.Details["max"])
+			require.Equal(t, tt.requested, domainErr.Details["requested"])
+		})
+	}
+}
+
+func TestCartService_AddProduct_CountOverflow(t *testing.T) {
+	items := []model.CartItem{{Id: 1, SkuId: 10, Count: math.MaxInt32 - 1}}
+	svc := NewCartService(newLimitsCartRepo(t, items), nil, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	err := svc.AddProduct(context.Background(), uuid.New(), 10, 2)
+	require.ErrorIs(t, err, model.ErrCartLimitExceeded)
+
+	var domainErr *model.Error
+	require.ErrorAs(t, err, &domainErr)
+	require.Equal(t, uint64(math.MaxInt32), domainErr.Details["max"])
+	require.Equal(t, uint64(math.MaxInt32)+1, domainErr.Details["requested"])
+}
+
+func TestCartService_SetProductCount_LimitExceeded(t *testing.T) {
+	items := []model.CartItem{{Id: 1, SkuId: 10, Count: 8}}
+	limits := model.CartLimits{MaxUnitsPerSku: 10}
+	svc := NewCartService(newLimitsCartRepo(t, items), nil, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, limits)
+
+	err := svc.SetProductCount(context.Background(), uuid.New(), 10, 11)
+	require.ErrorIs(t, err, model.ErrCartLimitExceeded)
+}
+
+func TestCartService_SetProductCount_WithinLimits(t *testing.T) {
+	var updated uint32
+	cartRepo := newLimitsCartRepo(t, []model.CartItem{{Id: 1, SkuId: 10, Count: 8}})
+	cartRepo.updateFn = func(ctx context.Context, id uint64, item model.CartItem) error {
+		updated = item.Count
+		return nil
+	}
+	limits := model.CartLimits{MaxUnitsPerSku: 10, MaxDistinctSkus: 1, MaxTotalUnits: 10}
+	svc := NewCartService(cartRepo, nil, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, limits)
+
+	// Уменьшение количества не должно упираться в лимиты, даже если корзина уже заполнена
+	require.NoError(t, svc.SetProductCount(context.Background(), uuid.New(), 10, 10))
+	require.Equal(t, uint32(10), updated)
+}
+
+func TestCartService_SetProductCount_ShrinkOverLimitCart(t *testing.T) {
+	// Лимиты уменьшили после того, как корзину наполнили
+	items := []model.CartItem{
+		{Id: 1, SkuId: 10, Count: 8},
+		{Id: 2, SkuId: 20, Count: 6},
+		{Id: 3, SkuId: 30, Count: 4},
+	}
+	limits := model.CartLimits{MaxUnitsPerSku: 5, MaxDistinctSkus: 2, MaxTotalUnits: 10}
+
+	tests := []struct {
+		name  string
+		sku   uint64
+		count uint32
+	}{
+		{name: "still over units per sku", sku: 10, count: 7},
+		{name: "below units per sku", sku: 20, count: 1},
+		{name: "same count", sku: 30, count: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated uint32
+			cartRepo := newLimitsCartRepo(t, items)
+			cartRepo.updateFn = func(ctx context.Context, id uint64, item model.CartItem) error {
+				updated = item.Count
+				return nil
+			}
+			svc := NewCartService(cartRepo, nil, &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, limits)
+
+			require.NoError(t, svc.SetProductCount(context.Background(), uuid.New(), tt.sku, tt.count))
+			require.Equal(t, tt.count, updated)
+		})
+	}
+}
+
+func TestCartService_GrowOverLimitCart(t *testing.T) {
+	items := []model.CartItem{
+		{Id: 1, SkuId: 10, Count: 4},
+		{Id: 2, SkuId: 20, Count: 4},
+		{Id: 3, SkuId: 30, Count: 4},
+	}
+	limits := model.CartLimits{MaxDistinctSkus: 2, MaxTotalUnits: 10}
+	svc := NewCartService(newLimitsCartRepo(t, items), newBatchProductService(), &stubStockService{}, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, limits)
+
+	// Новый sku увеличивает число позиций, которое и так больше лимита
+	err := svc.AddProduct(context.Background(), uuid.New(), 40, 1)
+	require.ErrorIs(t, err, model.ErrCartLimitExceeded)
+
+	// Увеличение существующей позиции увеличивает общее число единиц
+	err = svc.SetProductCount(context.Background(), uuid.New(), 10, 5)
+	require.ErrorIs(t, err, model.ErrCartLimitExceeded)
+
+	var domainErr *model.Error
+	require.ErrorAs(t, err, &domainErr)
+	require.Equal(t, string(model.CartLimitTotalUnits), domainErr.Details["limit"])
+	require.Equal(t, uint64(13), domainErr.Details["requested"])
+}
+
+func stockCounts(counts map[uint64]uint64) *stubStockService {
+	return &stubStockService{
+		countFn: func(ctx context.Context, sku uint64) (uint64, error) {
+			return counts[sku], nil
+		},
+	}
+}
+
+func TestCartService_AddProduct_InsufficientStock(t *testing.T) {
+	cartRepo := &stubCartRepo{
+		getItemFn: func(ctx context.Context, uid uuid.UUID, sku uint64) (*model.CartItem, error) {
+			return &model.CartItem{Id: 1, UserId: uid, SkuId: sku, Count: 4}, nil
+		},
+		upsertFn: func(ctx context.Context, item model.CartItem) (*model.CartItem, error) {
+			t.Fatal("cart must not be changed")
+			return nil, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, nil, stockCounts(map[uint64]uint64{10: 5}), nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	// В корзине уже 4 единицы, поэтому добавить 2 при остатке 5 нельзя
+	err := svc.AddProduct(context.Background(), uuid.New(), 10, 2)
+	require.ErrorIs(t, err, model.ErrInsufficientStock)
+
+	var domainErr *model.Error
+	require.ErrorAs(t, err, &domainErr)
+	require.Equal(t, uint64(10), domainErr.Details["sku"])
+	require.Equal(t, uint64(5), domainErr.Details["available"])
+	require.Equal(t, uint64(6), domainErr.Details["requested"])
+}
+
+func TestCartService_SetProductCount_InsufficientStock(t *testing.T) {
+	cartRepo := &stubCartRepo{
+		getItemFn: func(ctx context.Context, uid uuid.UUID, sku uint64) (*model.CartItem, error) {
+			return &model.CartItem{Id: 1, UserId: uid, SkuId: sku, Count: 4}, nil
+		},
+		updateFn: func(ctx context.Context, id uint64, item model.CartItem) error {
+			t.Fatal("cart must not be changed")
+			return nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, nil, stockCounts(map[uint64]uint64{10: 5}), nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	err := svc.SetProductCount(context.Background(), uuid.New(), 10, 6)
+	require.ErrorIs(t, err, model.ErrInsufficientStock)
+}
+
+func TestCartService_SetProductCount_StockUnavailable(t *testing.T) {
+	stockSrv := &stubStockService{
+		countFn: func(ctx context.Context, sku uint64) (uint64, error) {
+			return 0, model.ErrStockServiceUnavailable
+		},
+	}
+
+	svc := NewCartService(&stubCartRepo{}, existingProducts(), stockSrv, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	err := svc.SetProductCount(context.Background(), uuid.New(), 10, 1)
+	require.ErrorIs(t, err, model.ErrStockServiceUnavailable)
+}
+
+func TestCartService_SetProductCount_DecreaseSkipsStockCheck(t *testing.T) {
+	updated := false
+	cartRepo := &stubCartRepo{
+		getItemFn: func(ctx context.Context, uid uuid.UUID, sku uint64) (*model.CartItem, error) {
+			return &model.CartItem{Id: 1, UserId: uid, SkuId: sku, Count: 4}, nil
+		},
+		updateFn: func(ctx context.Context, id uint64, item model.CartItem) error {
+			require.Equal(t, uint32(2), item.Count)
+			updated = true
+			return nil
+		},
+	}
+
+	stockSrv := &stubStockService{
+		countFn: func(ctx context.Context, sku uint64) (uint64, error) {
+			t.Fatal("stock must not be checked when count decreases")
+			return 0, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, nil, stockSrv, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	require.NoError(t, svc.SetProductCount(context.Background(), uuid.New(), 10, 2))
+	require.True(t, updated)
+}
+
+func TestCartService_Checkout_InsufficientStock(t *testing.T) {
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			return []model.CartItem{
+				{UserId: uid, SkuId: 1, Count: 2},
+				{UserId: uid, SkuId: 2, Count: 3},
+			}, nil
+		},
+	}
+
+	orderSrv := &stubOrderService{
+		createFn: func(ctx context.Context, uid uuid.UUID, orderItems []model.CartItem) (int64, error) {
+			t.Fatal("order must not be created")
+			return 0, nil
+		},
+	}
+
+	stockSrv := stockCounts(map[uint64]uint64{1: 2, 2: 1})
+	svc := NewCartService(cartRepo, existingProducts(), stockSrv, orderSrv, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	_, err := svc.Checkout(context.Background(), uuid.New())
+	require.ErrorIs(t, err, model.ErrInsufficientStock)
+
+	var domainErr *model.Error
+	require.ErrorAs(t, err, &domainErr)
+	require.Equal(t, uint64(2), domainErr.Details["sku"])
+}
+
+func TestCartService_GetCart_FlagsOutOfStock(t *testing.T) {
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			return []model.CartItem{
+				{UserId: uid, SkuId: 1, Count: 2},
+				{UserId: uid, SkuId: 2, Count: 3},
+				{UserId: uid, SkuId: 3, Count: 1},
+			}, nil
+		},
+	}
+
+	stockSrv := stockCounts(map[uint64]uint64{1: 2, 2: 1})
+	svc := NewCartService(cartRepo, existingProducts(), stockSrv, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	cart, err := svc.GetCart(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.False(t, cart.Items[0].OutOfStock)
+	require.True(t, cart.Items[1].OutOfStock)
+	require.True(t, cart.Items[2].OutOfStock)
+}
+
+func TestCartService_GetCart_StockServiceUnavailable(t *testing.T) {
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			return []model.CartItem{
+				{UserId: uid, SkuId: 1, Count: 2},
+				{UserId: uid, SkuId: 2, Count: 3},
+			}, nil
+		},
+	}
+
+	stockSrv := &stubStockService{
+		countFn: func(ctx context.Context, sku uint64) (uint64, error) {
+			if sku == 2 {
+				return 0, model.ErrStockServiceUnavailable
+			}
+			return 1, nil
+		},
+	}
+	svc := NewCartService(cartRepo, existingProducts(), stockSrv, nil, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	// Корзина отдается и без склада, остаток позиции просто помечается неизвестным
+	cart, err := svc.GetCart(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.False(t, cart.Items[0].StockUnknown)
+	require.True(t, cart.Items[0].OutOfStock)
+	require.True(t, cart.Items[1].StockUnknown)
+	require.False(t, cart.Items[1].OutOfStock)
+}
+
+func TestCartService_Checkout_VersionMismatch(t *testing.T) {
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			return []model.CartItem{{UserId: uid, SkuId: 1, Count: 1}}, nil
+		},
+		removeAllFn: func(ctx context.Context, uid uuid.UUID) (bool, error) {
+			t.Fatal("cart must not be cleared")
+			return true, nil
+		},
+		versionFn: func(ctx context.Context, uid uuid.UUID) (uint64, error) {
+			return 4, nil
+		},
+	}
+
+	orderSrv := &stubOrderService{
+		createFn: func(ctx context.Context, uid uuid.UUID, orderItems []model.CartItem) (int64, error) {
+			t.Fatal("order must not be created")
+			return 0, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, existingProducts(), &stubStockService{}, orderSrv, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	ctx := model.WithExpectedCartVersions(context.Background(), []uint64{3})
+	_, err := svc.Checkout(ctx, uuid.New())
+	require.ErrorIs(t, err, model.ErrCartVersionMismatch)
+}
+
+func TestCartService_Checkout_LocksCartAfterExternalChecks(t *testing.T) {
+	var calls []string
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			calls = append(calls, "get")
+			return []model.CartItem{{UserId: uid, SkuId: 1, Count: 1}}, nil
+		},
+		removeAllFn: func(ctx context.Context, uid uuid.UUID) (bool, error) {
+			calls = append(calls, "clear")
+			return true, nil
+		},
+		versionFn: func(ctx context.Context, uid uuid.UUID) (uint64, error) {
+			calls = append(calls, "lock")
+			return 1, nil
+		},
+	}
+
+	productSrv := &stubProductService{
+		getBatchFn: func(ctx context.Context, skus []uint64) (map[uint64]*model.Product, error) {
+			calls = append(calls, "products")
+			return map[uint64]*model.Product{1: {Sku: 1}}, nil
+		},
+	}
+
+	stockSrv := &stubStockService{
+		countFn: func(ctx context.Context, sku uint64) (uint64, error) {
+			calls = append(calls, "stock")
+			return 1, nil
+		},
+	}
+
+	orderSrv := &stubOrderService{
+		createFn: func(ctx context.Context, uid uuid.UUID, orderItems []model.CartItem) (int64, error) {
+			calls = append(calls, "order")
+			return 1, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, productSrv, stockSrv, orderSrv, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	// Каталог и склад опрашиваются до блокировки корзины, под блокировкой только заказ и очистка
+	_, err := svc.Checkout(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.Equal(t, []string{"get", "products", "stock", "lock", "get", "order", "clear"}, calls)
+}
+
+func TestCartService_Checkout_CartChangedAfterChecks(t *testing.T) {
+	reads := 0
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			reads++
+			if reads == 1 {
+				return []model.CartItem{{Id: 1, UserId: uid, SkuId: 1, Count: 2}}, nil
+			}
+			return []model.CartItem{{Id: 1, UserId: uid, SkuId: 1, Count: 5}}, nil
+		},
+	}
+
+	orderSrv := &stubOrderService{
+		createFn: func(ctx context.Context, uid uuid.UUID, orderItems []model.CartItem) (int64, error) {
+			t.Fatal("order must not be created")
+			return 0, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, existingProducts(), &stubStockService{}, orderSrv, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	_, err := svc.Checkout(context.Background(), uuid.New())
+	require.ErrorIs(t, err, model.ErrCartChangedDuringCheckout)
+}
+
+func TestCartService_Checkout_OrderIdempotencyKey(t *testing.T) {
+	userId := uuid.New()
+	cartRepo := &stubCartRepo{
+		getFn: func(ctx context.Context, uid uuid.UUID) ([]model.CartItem, error) {
+			return []model.CartItem{{UserId: uid, SkuId: 1, Count: 1}}, nil
+		},
+		removeAllFn: func(ctx context.Context, uid uuid.UUID) (bool, error) {
+			return false, errors.New("commit failed")
+		},
+		versionFn: func(ctx context.Context, uid uuid.UUID) (uint64, error) {
+			return 7, nil
+		},
+	}
+
+	orderSrv := &stubOrderService{
+		createFn: func(ctx context.Context, uid uuid.UUID, orderItems []model.CartItem) (int64, error) {
+			return 1, nil
+		},
+	}
+
+	svc := NewCartService(cartRepo, existingProducts(), &stubStockService{}, orderSrv, nil, &stubOutboxRepo{}, &stubTransactor{}, model.CartLimits{})
+
+	// Повтор после неудачной очистки отправляет тот же ключ, поэтому LOMS не создаст второй заказ
+	for i := 0; i < 2; i++ {
+		_, err := svc.Checkout(context.Background(), userId)
+		require.Error(t, err)
+	}
+
+	key := "cart-checkout:" + userId.String() + ":7"
+	require.Equal(t, []string{key, key}, orderSrv.keys)
+}
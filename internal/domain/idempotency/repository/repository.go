@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+)
+
+type recordKey struct {
+	userId uuid.UUID
+	key    string
+}
+
+type InMemoryIdempotencyRepository struct {
+	records map[recordKey]model.IdempotencyRecord
+	mutex   sync.Mutex
+}
+
+func NewInMemoryIdempotencyRepository() *InMemoryIdempotencyRepository {
+	return &InMemoryIdempotencyRepository{
+		records: make(map[recordKey]model.IdempotencyRecord),
+	}
+}
+
+func (r *InMemoryIdempotencyRepository) Acquire(
+	_ context.Context,
+	record model.IdempotencyRecord,
+	now time.Time,
+) (*model.IdempotencyRecord, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := recordKey{userId: record.UserId, key: record.Key}
+
+	if existing, ok := r.records[key]; ok && existing.ExpiresAt.After(now) {
+		return &existing, nil
+	}
+
+	record.StatusCode = 0
+	record.ContentType = ""
+	record.Body = nil
+	r.records[key] = record
+
+	return nil, nil
+}
+
+// Complete сохраняет ответ, если ключ все еще занят запросом с блокировкой до lockedUntil.
+// Если блокировка истекла и ключ занял другой запрос, ответ не сохраняется.
+func (r *InMemoryIdempotencyRepository) Complete(
+	_ context.Context,
+	record model.IdempotencyRecord,
+	lockedUntil time.Time,
+) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := recordKey{userId: record.UserId, key: record.Key}
+	existing, ok := r.records[key]
+	if !ok || !isLockedUntil(existing, lockedUntil) {
+		return nil
+	}
+
+	existing.StatusCode = record.StatusCode
+	existing.ContentType = record.ContentType
+	existing.Body = append([]byte(nil), record.Body...)
+	existing.ExpiresAt = record.ExpiresAt
+	r.records[key] = existing
+
+	return nil
+}
+
+// Release освобождает ключ, если он все еще занят запросом с блокировкой до lockedUntil
+func (r *InMemoryIdempotencyRepository) Release(_ context.Context, userId uuid.UUID, key string, lockedUntil time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if existing, ok := r.records[recordKey{userId: userId, key: key}]; ok && isLockedUntil(existing, lockedUntil) {
+		delete(r.records, recordKey{userId: userId, key: key})
+	}
+
+	return nil
+}
+
+func (r *InMemoryIdempotencyRepository) DeleteExpired(_ context.Context, now time.Time) (int64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var deleted int64
+	for key, record := range r.records {
+		if !record.ExpiresAt.After(now) {
+			delete(r.records, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func isLockedUntil(record model.IdempotencyRecord, lockedUntil time.Time) bool {
+	return !record.Completed() && record.ExpiresAt.Equal(lockedUntil)
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/database"
+)
+
+type PgxIdempotencyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPgxIdempotencyRepository(pool *pgxpool.Pool) *PgxIdempotencyRepository {
+	return &PgxIdempotencyRepository{pool: pool}
+}
+
+// Acquire занимает ключ под новый запрос до record.ExpiresAt. Если ключ уже занят и не истек, возвращает существующую запись.
+func (r *PgxIdempotencyRepository) Acquire(
+	ctx context.Context,
+	record model.IdempotencyRecord,
+	now time.Time,
+) (*model.IdempotencyRecord, error) {
+	// Истекшая запись перезаписывается, живая остается без изменений
+	const insertQuery = `
+INSERT INTO 
+    idempotency_keys (user_id, key, request_hash, expires_at) 
+VALUES 
+    ($1, $2, $3, $4)
+ON CONFLICT (user_id, key) DO UPDATE 
+SET 
+    request_hash = EXCLUDED.request_hash,
+    status_code = NULL,
+    content_type = NULL,
+    body = NULL,
+    created_at = now(),
+    expires_at = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= $5
+RETURNING user_id`
+
+	const selectQuery = `
+SELECT request_hash, status_code, content_type, body, expires_at
+FROM idempotency_keys 
+WHERE user_id = $1 AND key = $2`
+
+	querier := database.QuerierFromContext(ctx, r.pool)
+
+	var userId uuid.UUID
+	err := querier.QueryRow(ctx, insertQuery, record.UserId, record.Key, record.RequestHash, record.ExpiresAt, now).
+		Scan(&userId)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("PgxIdempotencyRepository.Acquire: %w", err)
+	}
+
+	existing := model.IdempotencyRecord{UserId: record.UserId, Key: record.Key}
+
+	var (
+		statusCode  *int
+		contentType *string
+	)
+	err = querier.QueryRow(ctx, selectQuery, record.UserId, record.Key).
+		Scan(&existing.RequestHash, &statusCode, &contentType, &existing.Body, &existing.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Запись успели освободить между запросами, клиент может повторить попытку
+		return nil, model.ErrIdempotencyKeyInProgress
+	}
+	if err != nil {
+		return nil, fmt.Errorf("PgxIdempotencyRepository.Acquire: %w", err)
+	}
+
+	if statusCode != nil {
+		existing.StatusCode = *statusCode
+	}
+	if contentType != nil {
+		existing.ContentType = *contentType
+	}
+
+	return &existing, nil
+}
+
+// Complete сохраняет ответ, если ключ все еще занят запросом с блокировкой до lockedUntil.
+// Если блокировка истекла и ключ занял другой запрос, ответ не сохраняется.
+func (r *PgxIdempotencyRepository) Complete(
+	ctx context.Context,
+	record model.IdempotencyRecord,
+	lockedUntil time.Time,
+) error {
+	const query = `
+UPDATE idempotency_keys 
+SET status_code = $3, content_type = $4, body = $5, expires_at = $6
+WHERE user_id = $1 AND key = $2 AND status_code IS NULL AND expires_at = $7`
+
+	_, err := database.QuerierFromContext(ctx, r.pool).Exec(
+		ctx,
+		query,
+		record.UserId,
+		record.Key,
+		record.StatusCode,
+		record.ContentType,
+		record.Body,
+		record.ExpiresAt,
+		lockedUntil,
+	)
+	if err != nil {
+		return fmt.Errorf("PgxIdempotencyRepository.Complete: %w", err)
+	}
+
+	return nil
+}
+
+// Release освобождает ключ, если он все еще занят запросом с блокировкой до lockedUntil
+func (r *PgxIdempotencyRepository) Release(ctx context.Context, userId uuid.UUID, key string, lockedUntil time.Time) error {
+	const query = `
+DELETE FROM idempotency_keys 
+WHERE user_id = $1 AND key = $2 AND status_code IS NULL AND expires_at = $3`
+
+	_, err := database.QuerierFromContext(ctx, r.pool).Exec(ctx, query, userId, key, lockedUntil)
+	if err != nil {
+		return fmt.Errorf("PgxIdempotencyRepository.Release: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PgxIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	const query = `
+DELETE FROM idempotency_keys 
+WHERE expires_at <= $1`
+
+	tag, err := database.QuerierFromContext(ctx, r.pool).Exec(ctx, query, now)
+	if err != nil {
+		return 0, fmt.Errorf("PgxIdempotencyRepository.DeleteExpired: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
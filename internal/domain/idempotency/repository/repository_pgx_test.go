@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+// Интеграционные тесты запускаются только против локального Postgres с примененными миграциями
+const testDatabaseDsnEnv = "CART_TEST_DATABASE_DSN"
+
+func TestPgxIdempotencyRepository(t *testing.T) {
+	dsn := os.Getenv(testDatabaseDsnEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseDsnEnv)
+	}
+
+	pool, err := pgxpool.New(context.Background(), dsn)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	repo := NewPgxIdempotencyRepository(pool)
+	ctx := context.Background()
+	now := time.Now()
+	record := model.IdempotencyRecord{
+		UserId:      uuid.New(),
+		Key:         "key",
+		RequestHash: "hash",
+		ExpiresAt:   now.Add(time.Hour),
+	}
+	t.Cleanup(func() {
+		_ = repo.Release(ctx, record.UserId, record.Key, record.ExpiresAt)
+	})
+
+	existing, err := repo.Acquire(ctx, record, now)
+	require.NoError(t, err)
+	require.Nil(t, existing)
+
+	existing, err = repo.Acquire(ctx, record, now)
+	require.NoError(t, err)
+	require.False(t, existing.Completed())
+
+	completed := record
+	completed.StatusCode = 200
+	completed.ContentType = "application/json"
+	completed.Body = []byte(`{}`)
+	completed.ExpiresAt = now.Add(2 * time.Hour)
+	require.NoError(t, repo.Complete(ctx, completed, record.ExpiresAt))
+
+	existing, err = repo.Acquire(ctx, record, now)
+	require.NoError(t, err)
+	require.Equal(t, 200, existing.StatusCode)
+	require.Equal(t, "hash", existing.RequestHash)
+	require.Equal(t, []byte(`{}`), existing.Body)
+
+	existing, err = repo.Acquire(ctx, record, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.Nil(t, existing)
+}
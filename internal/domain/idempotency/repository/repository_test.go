@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryIdempotencyRepository(t *testing.T) {
+	repo := NewInMemoryIdempotencyRepository()
+	ctx := context.Background()
+	now := time.Now()
+	record := model.IdempotencyRecord{
+		UserId:      uuid.New(),
+		Key:         "key",
+		RequestHash: "hash",
+		ExpiresAt:   now.Add(time.Hour),
+	}
+
+	existing, err := repo.Acquire(ctx, record, now)
+	require.NoError(t, err)
+	require.Nil(t, existing)
+
+	existing, err = repo.Acquire(ctx, record, now)
+	require.NoError(t, err)
+	require.NotNil(t, existing)
+	require.False(t, existing.Completed())
+
+	completed := record
+	completed.StatusCode = 200
+	completed.ContentType = "application/json"
+	completed.Body = []byte(`{}`)
+	completed.ExpiresAt = now.Add(2 * time.Hour)
+	require.NoError(t, repo.Complete(ctx, completed, record.ExpiresAt))
+
+	existing, err = repo.Acquire(ctx, record, now)
+	require.NoError(t, err)
+	require.Equal(t, 200, existing.StatusCode)
+	require.Equal(t, "application/json", existing.ContentType)
+	require.Equal(t, []byte(`{}`), existing.Body)
+
+	// Ключ другого пользователя не пересекается с первым
+	other := record
+	other.UserId = uuid.New()
+	existing, err = repo.Acquire(ctx, other, now)
+	require.NoError(t, err)
+	require.Nil(t, existing)
+
+	// Истекший ключ занимается заново
+	existing, err = repo.Acquire(ctx, record, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.Nil(t, existing)
+
+	deleted, err := repo.DeleteExpired(ctx, now.Add(3*time.Hour))
+	require.NoError(t, err)
+	require.EqualValues(t, 2, deleted)
+
+	require.NoError(t, repo.Release(ctx, record.UserId, record.Key, record.ExpiresAt))
+}
+
+func TestInMemoryIdempotencyRepository_ExpiredLockTakeover(t *testing.T) {
+	repo := NewInMemoryIdempotencyRepository()
+	ctx := context.Background()
+	now := time.Now()
+
+	first := model.IdempotencyRecord{
+		UserId:      uuid.New(),
+		Key:         "key",
+		RequestHash: "hash",
+		ExpiresAt:   now.Add(time.Minute),
+	}
+	existing, err := repo.Acquire(ctx, first, now)
+	require.NoError(t, err)
+	require.Nil(t, existing)
+
+	// Первый запрос завис, после истечения блокировки ключ занимает повтор
+	second := first
+	second.ExpiresAt = now.Add(3 * time.Minute)
+	existing, err = repo.Acquire(ctx, second, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	require.Nil(t, existing)
+
+	// Завершение и освобождение по чужой блокировке ничего не меняют
+	stale := first
+	stale.StatusCode = 500
+	stale.ExpiresAt = now.Add(time.Hour)
+	require.NoError(t, repo.Complete(ctx, stale, first.ExpiresAt))
+	require.NoError(t, repo.Release(ctx, first.UserId, first.Key, first.ExpiresAt))
+
+	existing, err = repo.Acquire(ctx, first, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	require.False(t, existing.Completed())
+	require.Equal(t, second.ExpiresAt, existing.ExpiresAt)
+
+	completed := second
+	completed.StatusCode = 200
+	completed.ExpiresAt = now.Add(time.Hour)
+	require.NoError(t, repo.Complete(ctx, completed, second.ExpiresAt))
+
+	existing, err = repo.Acquire(ctx, first, now.Add(30*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 200, existing.StatusCode)
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+)
+
+const defaultCleanupInterval = time.Minute
+
+type IdempotencyRepository interface {
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Cleaner периодически удаляет истекшие ключи идемпотентности, чтобы хранилище не росло бесконечно
+type Cleaner struct {
+	idempotencyRepository IdempotencyRepository
+	interval              time.Duration
+}
+
+func NewCleaner(idempotencyRepository IdempotencyRepository, interval time.Duration) *Cleaner {
+	if interval <= 0 {
+		interval = defaultCleanupInterval
+	}
+
+	return &Cleaner{
+		idempotencyRepository: idempotencyRepository,
+		interval:              interval,
+	}
+}
+
+// Run удаляет истекшие ключи, пока не будет отменен ctx.
+func (c *Cleaner) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := c.idempotencyRepository.DeleteExpired(ctx, time.Now())
+		if err != nil {
+			logger.FromContext(ctx).Error("idempotency cleaner: delete expired failed", "error", err)
+			continue
+		}
+
+		if deleted > 0 {
+			logger.FromContext(ctx).Debug("idempotency cleaner: expired keys deleted", "count", deleted)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+)
+
+type LomsService struct {
+	client  http.Client
+	address string
+}
+
+func NewLomsService(client http.Client, address string) *LomsService {
+	return &LomsService{
+		client:  client,
+		address: address,
+	}
+}
+
+type createOrderItem struct {
+	Sku   uint64 `json:"sku"`
+	Count uint32 `json:"count"`
+}
+
+type createOrderRequest struct {
+	User  uuid.UUID         `json:"user"`
+	Items []createOrderItem `json:"items"`
+}
+
+type createOrderResponse struct {
+	OrderId int64 `json:"orderID"`
+}
+
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// CreateOrder создает заказ в LOMS. idempotencyKey передается в заголовке Idempotency-Key,
+// чтобы повтор после сбоя не создал второй заказ
+func (s *LomsService) CreateOrder(ctx context.Context, userId uuid.UUID, items []model.CartItem, idempotencyKey string) (int64, error) {
+	body := createOrderRequest{
+		User:  userId,
+		Items: make([]createOrderItem, 0, len(items)),
+	}
+	for _, item := range items {
+		body.Items = append(body.Items, createOrderItem{Sku: item.SkuId, Count: item.Count})
+	}
+
+	payload, err := json.Marshal(&body)
+	if err != nil {
+		return 0, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("%s/order/create", s.address),
+		bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("http.NewRequestWithContext: %w", err)
+	}
+
+	request.Header.Add("Content-Type", "application/json")
+	if idempotencyKey != "" {
+		request.Header.Set(HeaderIdempotencyKey, idempotencyKey)
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, fmt.Errorf("client.Do: %w", err)
+		}
+
+		return 0, fmt.Errorf("%w: client.Do: %w", model.ErrOrderServiceUnavailable, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%w: status %d", mapCreateOrderStatus(response.StatusCode), response.StatusCode)
+	}
+
+	result := &createOrderResponse{}
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		return 0, err
+	}
+
+	return result.OrderId, nil
+}
+
+// mapCreateOrderStatus переводит код ответа LOMS в доменную ошибку: отказы по бизнес-причинам
+// отдаются клиенту как 4xx, а не как внутренняя ошибка
+func mapCreateOrderStatus(statusCode int) error {
+	switch {
+	case statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError:
+		return model.ErrOrderServiceUnavailable
+	case statusCode == http.StatusNotFound:
+		return model.ErrProductNotFound
+	case statusCode == http.StatusPreconditionFailed || statusCode == http.StatusConflict:
+		// LOMS отвечает так, когда не удалось зарезервировать остатки
+		return model.ErrInsufficientStock
+	default:
+		return model.ErrOrderRejected
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLomsService_CreateOrder(t *testing.T) {
+	userId := uuid.New()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/order/create", r.URL.Path)
+		require.Equal(t, "checkout-key", r.Header.Get(HeaderIdempotencyKey))
+
+		request := createOrderRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		require.Equal(t, userId, request.User)
+		require.Equal(t, []createOrderItem{{Sku: 1, Count: 2}, {Sku: 3, Count: 1}}, request.Items)
+
+		require.NoError(t, json.NewEncoder(w).Encode(createOrderResponse{OrderId: 42}))
+	}))
+	defer server.Close()
+
+	svc := NewLomsService(http.Client{}, server.URL)
+
+	orderId, err := svc.CreateOrder(context.Background(), userId, []model.CartItem{
+		{UserId: userId, SkuId: 1, Count: 2},
+		{UserId: userId, SkuId: 3, Count: 1},
+	}, "checkout-key")
+	require.NoError(t, err)
+	require.Equal(t, int64(42), orderId)
+}
+
+func TestLomsService_CreateOrder_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		err        error
+	}{
+		{name: "bad request", statusCode: http.StatusBadRequest, err: model.ErrOrderRejected},
+		{name: "product not found", statusCode: http.StatusNotFound, err: model.ErrProductNotFound},
+		{name: "insufficient stock", statusCode: http.StatusPreconditionFailed, err: model.ErrInsufficientStock},
+		{name: "too many requests", statusCode: http.StatusTooManyRequests, err: model.ErrOrderServiceUnavailable},
+		{name: "internal error", statusCode: http.StatusInternalServerError, err: model.ErrOrderServiceUnavailable},
+		{name: "bad gateway", statusCode: http.StatusBadGateway, err: model.ErrOrderServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			svc := NewLomsService(http.Client{}, server.URL)
+
+			_, err := svc.CreateOrder(context.Background(), uuid.New(), []model.CartItem{{SkuId: 1, Count: 1}}, "")
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestLomsService_CreateOrder_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	svc := NewLomsService(http.Client{}, server.URL)
+
+	_, err := svc.CreateOrder(context.Background(), uuid.New(), []model.CartItem{{SkuId: 1, Count: 1}}, "")
+	require.ErrorIs(t, err, model.ErrOrderServiceUnavailable)
+}
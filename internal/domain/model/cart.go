@@ -0,0 +1,27 @@
+package model
+
+import "github.com/google/uuid"
+
+type Cart struct {
+	UserId    uuid.UUID
+	Version   uint64
+	Items     []CartLine
+	Subtotal  float64
+	PromoCode string
+	Discounts []CartDiscount
+	// TotalPrice итоговая сумма: Subtotal за вычетом Discounts
+	TotalPrice float64
+}
+
+type CartLine struct {
+	CartItem
+	Name       string
+	Price      float64
+	TotalPrice float64
+	// OutOfStock выставляется, если доступного остатка меньше, чем товара в корзине
+	OutOfStock bool
+	// StockUnknown выставляется, если остаток не удалось узнать; OutOfStock в этом случае не выставляется
+	StockUnknown bool
+	// ProductUnavailable выставляется, если товар не удалось получить из каталога: имя и цена остаются пустыми
+	ProductUnavailable bool
+}
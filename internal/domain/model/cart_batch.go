@@ -0,0 +1,32 @@
+package model
+
+// MaxBatchOperations ограничивает число операций в одном пакетном изменении корзины
+const MaxBatchOperations = 100
+
+type CartItemAction string
+
+const (
+	CartItemActionAdd    CartItemAction = "add"
+	CartItemActionRemove CartItemAction = "remove"
+)
+
+type BatchMode string
+
+const (
+	// BatchModeAllOrNothing применяет операции, только если все они корректны
+	BatchModeAllOrNothing BatchMode = "all_or_nothing"
+	// BatchModeBestEffort применяет все корректные операции и пропускает остальные
+	BatchModeBestEffort BatchMode = "best_effort"
+)
+
+type CartItemOperation struct {
+	Action CartItemAction
+	SkuId  uint64
+	Count  uint32
+}
+
+type CartItemOperationResult struct {
+	Operation CartItemOperation
+	Applied   bool
+	Err       error
+}
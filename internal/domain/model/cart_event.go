@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CartEventType string
+
+const (
+	CartEventItemAdded        CartEventType = "item_added"
+	CartEventItemCountChanged CartEventType = "item_count_changed"
+	CartEventItemRemoved      CartEventType = "item_removed"
+	CartEventCartCleared      CartEventType = "cart_cleared"
+	CartEventCheckedOut       CartEventType = "checked_out"
+	CartEventCartExpired      CartEventType = "cart_expired"
+	CartEventPromoCodeApplied CartEventType = "promo_code_applied"
+	CartEventPromoCodeRemoved CartEventType = "promo_code_removed"
+)
+
+type CartEvent struct {
+	Type       CartEventType
+	UserId     uuid.UUID
+	SkuId      uint64
+	Count      uint32
+	OrderId    int64
+	PromoCode  string
+	OccurredAt time.Time
+}
@@ -1,10 +1,16 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type CartItem struct {
-	Id     uint64
-	SkuId  uint64
-	UserId uuid.UUID
-	Count  uint32
+	Id        uint64
+	SkuId     uint64
+	UserId    uuid.UUID
+	Count     uint32
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
@@ -0,0 +1,41 @@
+package model
+
+import "math"
+
+type CartLimit string
+
+const (
+	CartLimitUnitsPerSku  CartLimit = "max_units_per_sku"
+	CartLimitDistinctSkus CartLimit = "max_distinct_skus"
+	CartLimitTotalUnits   CartLimit = "max_total_units"
+)
+
+// CartLimits бизнес-ограничения на содержимое корзины. Нулевое значение снимает ограничение
+type CartLimits struct {
+	MaxUnitsPerSku  uint32
+	MaxDistinctSkus int
+	MaxTotalUnits   uint64
+}
+
+// UnitsPerSku возвращает действующий лимит количества одного товара: без явного лимита
+// количество все равно ограничено колонкой cart_items.count типа INT
+func (l CartLimits) UnitsPerSku() uint64 {
+	if l.MaxUnitsPerSku == 0 || l.MaxUnitsPerSku > math.MaxInt32 {
+		return math.MaxInt32
+	}
+
+	return uint64(l.MaxUnitsPerSku)
+}
+
+func NewCartLimitExceededError(limit CartLimit, max uint64, requested uint64) *Error {
+	return &Error{
+		Kind:    ErrCartLimitExceeded.Kind,
+		Code:    ErrCartLimitExceeded.Code,
+		Message: ErrCartLimitExceeded.Message,
+		Details: map[string]any{
+			"limit":     string(limit),
+			"max":       max,
+			"requested": requested,
+		},
+	}
+}
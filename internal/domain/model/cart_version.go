@@ -0,0 +1,26 @@
+package model
+
+import "context"
+
+type expectedCartVersionsKey struct{}
+
+// WithExpectedCartVersions задает версии корзины из If-Match: изменение применяется,
+// только если текущая версия корзины входит в versions
+func WithExpectedCartVersions(ctx context.Context, versions []uint64) context.Context {
+	return context.WithValue(ctx, expectedCartVersionsKey{}, versions)
+}
+
+func ExpectedCartVersionsFromContext(ctx context.Context) ([]uint64, bool) {
+	versions, ok := ctx.Value(expectedCartVersionsKey{}).([]uint64)
+
+	return versions, ok
+}
+
+func NewCartVersionMismatchError(currentVersion uint64) *Error {
+	return &Error{
+		Kind:    ErrCartVersionMismatch.Kind,
+		Code:    ErrCartVersionMismatch.Code,
+		Message: ErrCartVersionMismatch.Message,
+		Details: map[string]any{"version": currentVersion},
+	}
+}
@@ -1,8 +1,195 @@
 package model
 
-import "errors"
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrorKind определяет класс доменной ошибки, по нему ошибка отображается в код ответа
+type ErrorKind string
+
+const (
+	ErrorKindValidation    ErrorKind = "validation"
+	ErrorKindNotFound      ErrorKind = "not_found"
+	ErrorKindConflict      ErrorKind = "conflict"
+	ErrorKindUnprocessable ErrorKind = "unprocessable"
+	ErrorKindUnavailable   ErrorKind = "unavailable"
+
+	ErrorKindUnauthenticated ErrorKind = "unauthenticated"
+	ErrorKindForbidden       ErrorKind = "forbidden"
+	ErrorKindRateLimited     ErrorKind = "rate_limited"
+
+	ErrorKindPreconditionFailed ErrorKind = "precondition_failed"
+)
+
+// Error доменная ошибка со стабильным кодом, который отдается клиенту
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Details map[string]any
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is сравнивает ошибки по коду, чтобы errors.Is находил и копии с деталями
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+
+	return e.Code == t.Code
+}
+
+func NewValidationError(field string, message string) *Error {
+	return &Error{
+		Kind:    ErrorKindValidation,
+		Code:    "validation_failed",
+		Message: message,
+		Details: map[string]any{"field": field},
+	}
+}
 
 var (
-	ErrProductNotFound   = errors.New("product not found")
-	ErrCartItemsNotFound = errors.New("cartItems not found")
+	ErrProductNotFound = &Error{
+		Kind:    ErrorKindNotFound,
+		Code:    "product_not_found",
+		Message: "product not found",
+	}
+	ErrCartItemsNotFound = &Error{
+		Kind:    ErrorKindNotFound,
+		Code:    "cart_items_not_found",
+		Message: "cartItems not found",
+	}
+	ErrPromoCodeNotFound = &Error{
+		Kind:    ErrorKindNotFound,
+		Code:    "promo_code_not_found",
+		Message: "promo code not found",
+	}
+	ErrCartItemAlreadyExists = &Error{
+		Kind:    ErrorKindConflict,
+		Code:    "cart_item_already_exists",
+		Message: "cart item already exists",
+	}
+	ErrCartVersionMismatch = &Error{
+		Kind:    ErrorKindPreconditionFailed,
+		Code:    "cart_version_mismatch",
+		Message: "cart was modified by another request",
+	}
+	ErrCartChangedDuringCheckout = &Error{
+		Kind:    ErrorKindConflict,
+		Code:    "cart_changed_during_checkout",
+		Message: "cart was modified during checkout, retry the request",
+	}
+	ErrIdempotencyKeyInProgress = &Error{
+		Kind:    ErrorKindConflict,
+		Code:    "idempotency_key_in_progress",
+		Message: "request with this idempotency key is still in progress",
+	}
+	ErrIdempotencyKeyReused = &Error{
+		Kind:    ErrorKindUnprocessable,
+		Code:    "idempotency_key_reused",
+		Message: "idempotency key was already used with a different request",
+	}
+	ErrCartLimitExceeded = &Error{
+		Kind:    ErrorKindUnprocessable,
+		Code:    "cart_limit_exceeded",
+		Message: "cart limit exceeded",
+	}
+	ErrInsufficientStock = &Error{
+		Kind:    ErrorKindUnprocessable,
+		Code:    "insufficient_stock",
+		Message: "not enough product in stock",
+	}
+	ErrPromoCodeNotApplicable = &Error{
+		Kind:    ErrorKindUnprocessable,
+		Code:    "promo_code_not_applicable",
+		Message: "promo code conditions are not met",
+	}
+	ErrOrderRejected = &Error{
+		Kind:    ErrorKindUnprocessable,
+		Code:    "order_rejected",
+		Message: "order service rejected the order",
+	}
+	ErrBatchNotApplied = &Error{
+		Kind:    ErrorKindUnprocessable,
+		Code:    "batch_not_applied",
+		Message: "batch was not applied because some operations failed",
+	}
+
+	ErrProductServiceUnavailable = &Error{
+		Kind:    ErrorKindUnavailable,
+		Code:    "product_service_unavailable",
+		Message: "product service unavailable",
+	}
+	ErrOrderServiceUnavailable = &Error{
+		Kind:    ErrorKindUnavailable,
+		Code:    "order_service_unavailable",
+		Message: "order service unavailable",
+	}
+	ErrStockServiceUnavailable = &Error{
+		Kind:    ErrorKindUnavailable,
+		Code:    "stock_service_unavailable",
+		Message: "stock service unavailable",
+	}
+
+	ErrUnauthenticated = &Error{
+		Kind:    ErrorKindUnauthenticated,
+		Code:    "unauthenticated",
+		Message: "missing or invalid access token",
+	}
+	ErrForbidden = &Error{
+		Kind:    ErrorKindForbidden,
+		Code:    "forbidden",
+		Message: "access to another user's cart is forbidden",
+	}
+	ErrRateLimited = &Error{
+		Kind:    ErrorKindRateLimited,
+		Code:    "rate_limited",
+		Message: "too many requests",
+	}
 )
+
+// ProductsLookupError описывает товары, которые не удалось получить при пакетном запросе.
+// Остальные товары при этом возвращаются вызывающему коду.
+type ProductsLookupError struct {
+	Errors map[uint64]error
+}
+
+func (e *ProductsLookupError) Error() string {
+	skus := e.Skus()
+
+	parts := make([]string, 0, len(skus))
+	for _, sku := range skus {
+		parts = append(parts, fmt.Sprintf("sku %d: %v", sku, e.Errors[sku]))
+	}
+
+	return fmt.Sprintf("failed to get %d products: %s", len(skus), strings.Join(parts, "; "))
+}
+
+// Unwrap возвращает ошибки в порядке sku, чтобы errors.As находил одну и ту же ошибку
+func (e *ProductsLookupError) Unwrap() []error {
+	skus := e.Skus()
+
+	errs := make([]error, 0, len(skus))
+	for _, sku := range skus {
+		errs = append(errs, e.Errors[sku])
+	}
+
+	return errs
+}
+
+// Skus возвращает отсортированный список sku, которые не удалось получить
+func (e *ProductsLookupError) Skus() []uint64 {
+	skus := make([]uint64, 0, len(e.Errors))
+	for sku := range e.Errors {
+		skus = append(skus, sku)
+	}
+	sort.Slice(skus, func(i, j int) bool { return skus[i] < skus[j] })
+
+	return skus
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyRecord сохраненный ответ на запрос с заголовком Idempotency-Key.
+// Пока запрос обрабатывается, StatusCode равен нулю.
+type IdempotencyRecord struct {
+	UserId      uuid.UUID
+	Key         string
+	RequestHash string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+type OutboxMessage struct {
+	Id        uint64
+	Key       string
+	EventType string
+	Payload   []byte
+	CreatedAt time.Time
+}
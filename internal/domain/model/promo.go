@@ -0,0 +1,54 @@
+package model
+
+import "strings"
+
+type PromoRuleType string
+
+const (
+	PromoRulePercentOff   PromoRuleType = "percent_off"
+	PromoRuleAmountOff    PromoRuleType = "amount_off"
+	PromoRuleBuyXGetY     PromoRuleType = "buy_x_get_y"
+	PromoRuleMinCartTotal PromoRuleType = "min_cart_total"
+)
+
+// PromoRule правило промокода. Используемые поля зависят от Type:
+// percent_off — Percent и необязательный SkuId, amount_off — Amount,
+// buy_x_get_y — SkuId, BuyCount и FreeCount, min_cart_total — Amount как минимальная сумма корзины
+type PromoRule struct {
+	Type      PromoRuleType
+	Percent   float64
+	Amount    float64
+	SkuId     uint64
+	BuyCount  uint32
+	FreeCount uint32
+}
+
+type PromoCode struct {
+	Code  string
+	Rules []PromoRule
+}
+
+// CartDiscount скидка, которую дало одно правило промокода
+type CartDiscount struct {
+	Code   string
+	Rule   PromoRuleType
+	SkuId  uint64
+	Amount float64
+}
+
+// NormalizePromoCode приводит код к виду, в котором он хранится: без пробелов по краям и в верхнем регистре
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func NewPromoCodeNotApplicableError(minTotal float64, subtotal float64) *Error {
+	return &Error{
+		Kind:    ErrPromoCodeNotApplicable.Kind,
+		Code:    ErrPromoCodeNotApplicable.Code,
+		Message: ErrPromoCodeNotApplicable.Message,
+		Details: map[string]any{
+			"min_cart_total": minTotal,
+			"subtotal":       subtotal,
+		},
+	}
+}
@@ -0,0 +1,14 @@
+package model
+
+func NewInsufficientStockError(sku uint64, available uint64, requested uint64) *Error {
+	return &Error{
+		Kind:    ErrInsufficientStock.Kind,
+		Code:    ErrInsufficientStock.Code,
+		Message: ErrInsufficientStock.Message,
+		Details: map[string]any{
+			"sku":       sku,
+			"available": available,
+			"requested": requested,
+		},
+	}
+}
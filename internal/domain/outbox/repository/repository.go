@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+)
+
+// InMemoryOutboxRepository хранит только неопубликованные сообщения.
+type InMemoryOutboxRepository struct {
+	messages []model.OutboxMessage
+	lastId   uint64
+	mutex    sync.Mutex
+}
+
+func NewInMemoryOutboxRepository() *InMemoryOutboxRepository {
+	return &InMemoryOutboxRepository{}
+}
+
+func (r *InMemoryOutboxRepository) AddCartEvent(_ context.Context, event model.CartEvent) error {
+	payload, err := marshalCartEvent(event)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.lastId++
+	r.messages = append(r.messages, model.OutboxMessage{
+		Id:        r.lastId,
+		Key:       event.UserId.String(),
+		EventType: string(event.Type),
+		Payload:   payload,
+		CreatedAt: time.Now().UTC(),
+	})
+
+	return nil
+}
+
+func (r *InMemoryOutboxRepository) GetUnpublishedMessages(_ context.Context, limit int) ([]model.OutboxMessage, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	result := make([]model.OutboxMessage, min(limit, len(r.messages)))
+	copy(result, r.messages)
+
+	return result, nil
+}
+
+func (r *InMemoryOutboxRepository) MarkMessagesPublished(_ context.Context, ids []uint64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	published := make(map[uint64]struct{}, len(ids))
+	for _, id := range ids {
+		published[id] = struct{}{}
+	}
+
+	messages := r.messages[:0]
+	for _, message := range r.messages {
+		if _, ok := published[message.Id]; !ok {
+			messages = append(messages, message)
+		}
+	}
+	r.messages = messages
+
+	return nil
+}
+
+// DeletePublishedMessages ничего не удаляет: опубликованные сообщения не хранятся
+func (r *InMemoryOutboxRepository) DeletePublishedMessages(_ context.Context, _ time.Time, _ int) (int64, error) {
+	return 0, nil
+}
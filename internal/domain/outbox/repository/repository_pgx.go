@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/database"
+)
+
+type PgxOutboxRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPgxOutboxRepository(pool *pgxpool.Pool) *PgxOutboxRepository {
+	return &PgxOutboxRepository{pool: pool}
+}
+
+type CartEventPayload struct {
+	Type       model.CartEventType `json:"type"`
+	UserId     uuid.UUID           `json:"user_id"`
+	SkuId      uint64              `json:"sku_id,omitempty"`
+	Count      uint32              `json:"count,omitempty"`
+	OrderId    int64               `json:"order_id,omitempty"`
+	PromoCode  string              `json:"promo_code,omitempty"`
+	OccurredAt time.Time           `json:"occurred_at"`
+}
+
+func (r *PgxOutboxRepository) AddCartEvent(ctx context.Context, event model.CartEvent) error {
+	const query = `
+INSERT INTO 
+    outbox (key, event_type, payload) 
+VALUES 
+    ($1, $2, $3)`
+
+	payload, err := marshalCartEvent(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.QuerierFromContext(ctx, r.pool).
+		Exec(ctx, query, event.UserId.String(), string(event.Type), payload)
+	if err != nil {
+		return fmt.Errorf("PgxOutboxRepository.AddCartEvent: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PgxOutboxRepository) GetUnpublishedMessages(ctx context.Context, limit int) ([]model.OutboxMessage, error) {
+	const query = `
+SELECT 
+    id, key, event_type, payload, created_at 
+FROM 
+    outbox 
+WHERE 
+    published_at IS NULL
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED`
+
+	rows, err := database.QuerierFromContext(ctx, r.pool).Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("PgxOutboxRepository.GetUnpublishedMessages: %w", err)
+	}
+	defer rows.Close()
+
+	var result []model.OutboxMessage
+	for rows.Next() {
+		var message model.OutboxMessage
+		err = rows.Scan(
+			&message.Id,
+			&message.Key,
+			&message.EventType,
+			&message.Payload,
+			&message.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("PgxOutboxRepository.GetUnpublishedMessages: %w", err)
+		}
+
+		result = append(result, message)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("PgxOutboxRepository.GetUnpublishedMessages: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *PgxOutboxRepository) MarkMessagesPublished(ctx context.Context, ids []uint64) error {
+	const query = `
+UPDATE 
+    outbox
+SET
+    published_at = now()
+WHERE 
+    id = ANY($1)`
+
+	_, err := database.QuerierFromContext(ctx, r.pool).Exec(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("PgxOutboxRepository.MarkMessagesPublished: %w", err)
+	}
+
+	return nil
+}
+
+// DeletePublishedMessages удаляет не более limit сообщений, опубликованных раньше publishedBefore
+func (r *PgxOutboxRepository) DeletePublishedMessages(ctx context.Context, publishedBefore time.Time, limit int) (int64, error) {
+	const query = `
+DELETE FROM 
+    outbox
+WHERE 
+    id IN (
+        SELECT id 
+        FROM outbox 
+        WHERE published_at < $1
+        ORDER BY published_at
+        LIMIT $2
+    )`
+
+	tag, err := database.QuerierFromContext(ctx, r.pool).Exec(ctx, query, publishedBefore, limit)
+	if err != nil {
+		return 0, fmt.Errorf("PgxOutboxRepository.DeletePublishedMessages: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func marshalCartEvent(event model.CartEvent) ([]byte, error) {
+	payload, err := json.Marshal(&CartEventPayload{
+		Type:       event.Type,
+		UserId:     event.UserId,
+		SkuId:      event.SkuId,
+		Count:      event.Count,
+		OrderId:    event.OrderId,
+		PromoCode:  event.PromoCode,
+		OccurredAt: event.OccurredAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+)
+
+const (
+	defaultCleanupInterval  = 10 * time.Minute
+	defaultCleanupBatchSize = 1000
+)
+
+type PublishedOutboxRepository interface {
+	DeletePublishedMessages(ctx context.Context, publishedBefore time.Time, limit int) (int64, error)
+}
+
+// Cleaner удаляет опубликованные сообщения старше retention, чтобы outbox не рос бесконечно
+type Cleaner struct {
+	outboxRepository PublishedOutboxRepository
+	retention        time.Duration
+	interval         time.Duration
+	batchSize        int
+	now              func() time.Time
+}
+
+// NewCleaner нулевой retention отключает удаление; now позволяет подменить часы в тестах, nil означает time.Now
+func NewCleaner(
+	outboxRepository PublishedOutboxRepository,
+	retention time.Duration,
+	interval time.Duration,
+	batchSize int,
+	now func() time.Time,
+) *Cleaner {
+	if interval <= 0 {
+		interval = defaultCleanupInterval
+	}
+
+	if batchSize <= 0 {
+		batchSize = defaultCleanupBatchSize
+	}
+
+	if now == nil {
+		now = time.Now
+	}
+
+	return &Cleaner{
+		outboxRepository: outboxRepository,
+		retention:        retention,
+		interval:         interval,
+		batchSize:        batchSize,
+		now:              now,
+	}
+}
+
+// Run удаляет устаревшие опубликованные сообщения, пока не будет отменен ctx.
+func (c *Cleaner) Run(ctx context.Context) {
+	if c.retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			deleted, err := c.CleanBatch(ctx)
+			if err != nil {
+				logger.FromContext(ctx).Error("outbox cleaner: clean batch failed", "error", err)
+				break
+			}
+
+			if deleted > 0 {
+				logger.FromContext(ctx).Debug("outbox cleaner: published messages deleted", "count", deleted)
+			}
+
+			if deleted < c.batchSize {
+				break
+			}
+		}
+	}
+}
+
+// CleanBatch удаляет не более batchSize сообщений, опубликованных раньше retention, и возвращает их количество.
+func (c *Cleaner) CleanBatch(ctx context.Context) (int, error) {
+	deleted, err := c.outboxRepository.DeletePublishedMessages(ctx, c.now().Add(-c.retention), c.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("outboxRepository.DeletePublishedMessages: %w", err)
+	}
+
+	return int(deleted), nil
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type stubPublishedOutboxRepo struct {
+	publishedBefore time.Time
+	limit           int
+	deleted         int64
+	err             error
+}
+
+func (s *stubPublishedOutboxRepo) DeletePublishedMessages(_ context.Context, publishedBefore time.Time, limit int) (int64, error) {
+	s.publishedBefore = publishedBefore
+	s.limit = limit
+
+	return s.deleted, s.err
+}
+
+func TestCleaner_CleanBatch_OK(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	outboxRepo := &stubPublishedOutboxRepo{deleted: 5}
+
+	cleaner := NewCleaner(outboxRepo, 24*time.Hour, 0, 50, func() time.Time { return now })
+
+	deleted, err := cleaner.CleanBatch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 5, deleted)
+	require.Equal(t, now.Add(-24*time.Hour), outboxRepo.publishedBefore)
+	require.Equal(t, 50, outboxRepo.limit)
+}
+
+func TestCleaner_CleanBatch_RepoError(t *testing.T) {
+	outboxRepo := &stubPublishedOutboxRepo{err: errors.New("db down")}
+
+	cleaner := NewCleaner(outboxRepo, time.Hour, 0, 0, nil)
+
+	_, err := cleaner.CleanBatch(context.Background())
+	require.Error(t, err)
+}
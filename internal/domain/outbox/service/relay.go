@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultBatchSize    = 100
+)
+
+type OutboxRepository interface {
+	GetUnpublishedMessages(ctx context.Context, limit int) ([]model.OutboxMessage, error)
+	MarkMessagesPublished(ctx context.Context, ids []uint64) error
+}
+
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type Producer interface {
+	SendMessages(ctx context.Context, topic string, messages []model.OutboxMessage) error
+}
+
+type Relay struct {
+	outboxRepository OutboxRepository
+	transactor       Transactor
+	producer         Producer
+	topic            string
+	pollInterval     time.Duration
+	batchSize        int
+}
+
+func NewRelay(
+	outboxRepository OutboxRepository,
+	transactor Transactor,
+	producer Producer,
+	topic string,
+	pollInterval time.Duration,
+	batchSize int,
+) *Relay {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	return &Relay{
+		outboxRepository: outboxRepository,
+		transactor:       transactor,
+		producer:         producer,
+		topic:            topic,
+		pollInterval:     pollInterval,
+		batchSize:        batchSize,
+	}
+}
+
+// Run публикует сообщения из outbox, пока не будет отменен ctx.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			published, err := r.PublishBatch(ctx)
+			if err != nil {
+				logger.FromContext(ctx).Error("outbox relay: publish batch failed", "error", err)
+				break
+			}
+
+			if published < r.batchSize {
+				break
+			}
+		}
+	}
+}
+
+// PublishBatch отправляет одну пачку неопубликованных сообщений и возвращает их количество.
+// Сообщения помечаются опубликованными в той же транзакции, в которой были выбраны,
+// поэтому при ошибке отправки они будут повторно отправлены на следующей итерации.
+func (r *Relay) PublishBatch(ctx context.Context) (int, error) {
+	published := 0
+
+	err := r.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		messages, err := r.outboxRepository.GetUnpublishedMessages(ctx, r.batchSize)
+		if err != nil {
+			return fmt.Errorf("outboxRepository.GetUnpublishedMessages: %w", err)
+		}
+
+		if len(messages) == 0 {
+			return nil
+		}
+
+		if err = r.producer.SendMessages(ctx, r.topic, messages); err != nil {
+			return fmt.Errorf("producer.SendMessages: %w", err)
+		}
+
+		ids := make([]uint64, 0, len(messages))
+		for _, message := range messages {
+			ids = append(ids, message.Id)
+		}
+
+		if err = r.outboxRepository.MarkMessagesPublished(ctx, ids); err != nil {
+			return fmt.Errorf("outboxRepository.MarkMessagesPublished: %w", err)
+		}
+
+		published = len(messages)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return published, nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/kafka"
+	"github.com/stretchr/testify/require"
+)
+
+type stubOutboxRepo struct {
+	messages  []model.OutboxMessage
+	published map[uint64]bool
+}
+
+func (s *stubOutboxRepo) GetUnpublishedMessages(_ context.Context, limit int) ([]model.OutboxMessage, error) {
+	var result []model.OutboxMessage
+	for _, message := range s.messages {
+		if !s.published[message.Id] && len(result) < limit {
+			result = append(result, message)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *stubOutboxRepo) MarkMessagesPublished(_ context.Context, ids []uint64) error {
+	for _, id := range ids {
+		s.published[id] = true
+	}
+
+	return nil
+}
+
+type stubTransactor struct{}
+
+func (s *stubTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type failingProducer struct{}
+
+func (p *failingProducer) SendMessages(context.Context, string, []model.OutboxMessage) error {
+	return errors.New("broker unavailable")
+}
+
+func newStubOutboxRepo(count int) *stubOutboxRepo {
+	repo := &stubOutboxRepo{published: make(map[uint64]bool)}
+	for i := 1; i <= count; i++ {
+		repo.messages = append(repo.messages, model.OutboxMessage{
+			Id:        uint64(i),
+			Key:       "user",
+			EventType: string(model.CartEventItemAdded),
+			Payload:   []byte(`{}`),
+		})
+	}
+
+	return repo
+}
+
+func TestRelay_PublishBatch_OK(t *testing.T) {
+	outboxRepo := newStubOutboxRepo(3)
+	broker := kafka.NewInMemoryBroker()
+
+	relay := NewRelay(outboxRepo, &stubTransactor{}, broker, "cart-events", 0, 2)
+
+	published, err := relay.PublishBatch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, published)
+
+	published, err = relay.PublishBatch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, published)
+
+	published, err = relay.PublishBatch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0, published)
+
+	messages := broker.Messages("cart-events")
+	require.Len(t, messages, 3)
+	require.Equal(t, uint64(1), messages[0].Id)
+	require.Equal(t, uint64(3), messages[2].Id)
+}
+
+func TestRelay_PublishBatch_ProducerError(t *testing.T) {
+	outboxRepo := newStubOutboxRepo(1)
+
+	relay := NewRelay(outboxRepo, &stubTransactor{}, &failingProducer{}, "cart-events", 0, 10)
+
+	_, err := relay.PublishBatch(context.Background())
+	require.Error(t, err)
+	require.False(t, outboxRepo.published[1])
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/pkg/cache"
+	"golang.org/x/sync/singleflight"
+)
+
+type ProductProvider interface {
+	GetProductBySku(ctx context.Context, sku uint64) (*model.Product, error)
+	GetProductsBySkus(ctx context.Context, skus []uint64) (map[uint64]*model.Product, error)
+}
+
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+	Size   int
+}
+
+// cachedProduct с пустым product означает, что товар не найден
+type cachedProduct struct {
+	product *model.Product
+}
+
+type CachingProductService struct {
+	productService ProductProvider
+	cache          *cache.LRU[uint64, cachedProduct]
+	group          singleflight.Group
+	ttl            time.Duration
+	notFoundTtl    time.Duration
+
+	// inflight загрузки товаров, начатые GetProductsBySkus и еще не завершенные
+	inflightMutex sync.Mutex
+	inflight      map[uint64]*productFetch
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// productFetch загрузка одного товара в составе общего запроса; результат доступен после закрытия done
+type productFetch struct {
+	done    chan struct{}
+	product cachedProduct
+	// lookupErr ошибка получения конкретного товара, err ошибка запроса целиком
+	lookupErr error
+	err       error
+}
+
+func NewCachingProductService(
+	productService ProductProvider,
+	size int,
+	ttl time.Duration,
+	notFoundTtl time.Duration,
+) *CachingProductService {
+	return &CachingProductService{
+		productService: productService,
+		cache:          cache.NewLRU[uint64, cachedProduct](size),
+		ttl:            ttl,
+		notFoundTtl:    notFoundTtl,
+		inflight:       make(map[uint64]*productFetch),
+	}
+}
+
+func (s *CachingProductService) GetProductBySku(ctx context.Context, sku uint64) (*model.Product, error) {
+	if cached, ok := s.cache.Get(sku); ok {
+		s.hits.Add(1)
+
+		return cached.copyProduct()
+	}
+
+	s.misses.Add(1)
+
+	// Общий запрос не должен отменяться, если первый из ожидающих его вызовов ушел по таймауту
+	resultCh := s.group.DoChan(strconv.FormatUint(sku, 10), func() (any, error) {
+		product, err := s.productService.GetProductBySku(context.WithoutCancel(ctx), sku)
+		if err != nil {
+			if errors.Is(err, model.ErrProductNotFound) && s.notFoundTtl > 0 {
+				s.cache.Set(sku, cachedProduct{}, s.notFoundTtl)
+			}
+
+			return nil, err
+		}
+
+		cached := cachedProduct{product: product}
+		s.cache.Set(sku, cached, s.ttl)
+
+		return cached, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-resultCh:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+
+		return result.Val.(cachedProduct).copyProduct()
+	}
+}
+
+// GetProductsBySkus отдает товары из кэша и одним запросом догружает недостающие.
+func (s *CachingProductService) GetProductsBySkus(ctx context.Context, skus []uint64) (map[uint64]*model.Product, error) {
+	skus = uniqueSkus(skus)
+
+	products := make(map[uint64]*model.Product, len(skus))
+	lookupErrors := make(map[uint64]error)
+	missed := make([]uint64, 0, len(skus))
+
+	for _, sku := range skus {
+		cached, ok := s.cache.Get(sku)
+		if !ok {
+			s.misses.Add(1)
+			missed = append(missed, sku)
+
+			continue
+		}
+
+		s.hits.Add(1)
+
+		product, err := cached.copyProduct()
+		if err != nil {
+			lookupErrors[sku] = err
+
+			continue
+		}
+
+		products[sku] = product
+	}
+
+	if len(missed) > 0 {
+		fetched, err := s.fetchProducts(ctx, missed)
+		if err != nil {
+			return nil, err
+		}
+
+		for sku, cached := range fetched.products {
+			products[sku], _ = cached.copyProduct()
+		}
+
+		for sku, err := range fetched.errors {
+			lookupErrors[sku] = err
+		}
+	}
+
+	if len(lookupErrors) > 0 {
+		return products, &model.ProductsLookupError{Errors: lookupErrors}
+	}
+
+	return products, nil
+}
+
+// fetchedProducts результат загрузки недостающих товаров
+type fetchedProducts struct {
+	products map[uint64]cachedProduct
+	errors   map[uint64]error
+}
+
+// fetchProducts загружает товары, которых нет в кэше. Товары, которые уже загружаются другим вызовом,
+// не запрашиваются повторно: вызов дожидается чужой загрузки, а остальные товары запрашивает одним пакетом.
+func (s *CachingProductService) fetchProducts(ctx context.Context, skus []uint64) (fetchedProducts, error) {
+	fetches := make(map[uint64]*productFetch, len(skus))
+	owned := make([]uint64, 0, len(skus))
+
+	s.inflightMutex.Lock()
+	for _, sku := range skus {
+		fetch, ok := s.inflight[sku]
+		if !ok {
+			fetch = &productFetch{done: make(chan struct{})}
+			s.inflight[sku] = fetch
+			owned = append(owned, sku)
+		}
+
+		fetches[sku] = fetch
+	}
+	s.inflightMutex.Unlock()
+
+	if len(owned) > 0 {
+		slices.Sort(owned)
+
+		// Общая загрузка не должна отменяться, если вызов, который ее начал, ушел по таймауту
+		go s.loadProducts(context.WithoutCancel(ctx), owned, fetches)
+	}
+
+	result := fetchedProducts{
+		products: make(map[uint64]cachedProduct, len(skus)),
+		errors:   make(map[uint64]error),
+	}
+
+	for sku, fetch := range fetches {
+		select {
+		case <-ctx.Done():
+			return fetchedProducts{}, ctx.Err()
+		case <-fetch.done:
+		}
+
+		switch {
+		case fetch.err != nil:
+			return fetchedProducts{}, fetch.err
+		case fetch.lookupErr != nil:
+			result.errors[sku] = fetch.lookupErr
+		default:
+			result.products[sku] = fetch.product
+		}
+	}
+
+	return result, nil
+}
+
+// loadProducts одним запросом загружает товары skus, кладет их в кэш и завершает их загрузки в fetches
+func (s *CachingProductService) loadProducts(ctx context.Context, skus []uint64, fetches map[uint64]*productFetch) {
+	fetched, err := s.productService.GetProductsBySkus(ctx, skus)
+
+	var fetchErr *model.ProductsLookupError
+	if errors.As(err, &fetchErr) {
+		err = nil
+	}
+
+	for _, sku := range skus {
+		fetch := fetches[sku]
+
+		switch product, ok := fetched[sku]; {
+		case err != nil:
+			fetch.err = err
+		case ok:
+			fetch.product = cachedProduct{product: product}
+			s.cache.Set(sku, fetch.product, s.ttl)
+		case fetchErr != nil && fetchErr.Errors[sku] != nil:
+			fetch.lookupErr = fetchErr.Errors[sku]
+			if errors.Is(fetch.lookupErr, model.ErrProductNotFound) && s.notFoundTtl > 0 {
+				s.cache.Set(sku, cachedProduct{}, s.notFoundTtl)
+			}
+		default:
+			fetch.lookupErr = model.ErrProductNotFound
+		}
+	}
+
+	s.inflightMutex.Lock()
+	for _, sku := range skus {
+		delete(s.inflight, sku)
+	}
+	s.inflightMutex.Unlock()
+
+	for _, sku := range skus {
+		close(fetches[sku].done)
+	}
+}
+
+func (s *CachingProductService) Stats() CacheStats {
+	return CacheStats{
+		Hits:   s.hits.Load(),
+		Misses: s.misses.Load(),
+		Size:   s.cache.Len(),
+	}
+}
+
+func (c cachedProduct) copyProduct() (*model.Product, error) {
+	if c.product == nil {
+		return nil, model.ErrProductNotFound
+	}
+
+	product := *c.product
+
+	return &product, nil
+}
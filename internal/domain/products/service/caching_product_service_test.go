@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+type stubProductProvider struct {
+	calls     atomic.Int32
+	mutex     sync.Mutex
+	batchSkus [][]uint64
+	getFn     func(ctx context.Context, sku uint64) (*model.Product, error)
+}
+
+func (s *stubProductProvider) GetProductBySku(ctx context.Context, sku uint64) (*model.Product, error) {
+	s.calls.Add(1)
+
+	return s.getFn(ctx, sku)
+}
+
+func (s *stubProductProvider) GetProductsBySkus(ctx context.Context, skus []uint64) (map[uint64]*model.Product, error) {
+	s.mutex.Lock()
+	s.batchSkus = append(s.batchSkus, skus)
+	s.mutex.Unlock()
+
+	products := make(map[uint64]*model.Product, len(skus))
+	lookupErrors := make(map[uint64]error)
+	for _, sku := range skus {
+		product, err := s.getFn(ctx, sku)
+		if err != nil {
+			lookupErrors[sku] = err
+			continue
+		}
+
+		products[sku] = product
+	}
+
+	if len(lookupErrors) > 0 {
+		return products, &model.ProductsLookupError{Errors: lookupErrors}
+	}
+
+	return products, nil
+}
+
+func TestCachingProductService_CachesFoundProducts(t *testing.T) {
+	provider := &stubProductProvider{
+		getFn: func(ctx context.Context, sku uint64) (*model.Product, error) {
+			return &model.Product{Sku: sku, Name: "product", Price: 10}, nil
+		},
+	}
+
+	svc := NewCachingProductService(provider, 10, time.Minute, time.Second)
+
+	for i := 0; i < 3; i++ {
+		product, err := svc.GetProductBySku(context.Background(), 1)
+		require.NoError(t, err)
+		require.Equal(t, "product", product.Name)
+	}
+
+	require.Equal(t, int32(1), provider.calls.Load())
+	require.Equal(t, CacheStats{Hits: 2, Misses: 1, Size: 1}, svc.Stats())
+}
+
+func TestCachingProductService_CachesNotFound(t *testing.T) {
+	provider := &stubProductProvider{
+		getFn: func(ctx context.Context, sku uint64) (*model.Product, error) {
+			return nil, model.ErrProductNotFound
+		},
+	}
+
+	svc := NewCachingProductService(provider, 10, time.Minute, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		_, err := svc.GetProductBySku(context.Background(), 1)
+		require.ErrorIs(t, err, model.ErrProductNotFound)
+	}
+
+	require.Equal(t, int32(1), provider.calls.Load())
+}
+
+func TestCachingProductService_ReturnsCopies(t *testing.T) {
+	provider := &stubProductProvider{
+		getFn: func(ctx context.Context, sku uint64) (*model.Product, error) {
+			return &model.Product{Sku: sku, Name: "product"}, nil
+		},
+	}
+
+	svc := NewCachingProductService(provider, 10, time.Minute, time.Minute)
+
+	product, err := svc.GetProductBySku(context.Background(), 1)
+	require.NoError(t, err)
+	product.Name = "changed"
+
+	product, err = svc.GetProductBySku(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, "product", product.Name)
+}
+
+func TestCachingProductService_MergesConcurrentLookups(t *testing.T) {
+	release := make(chan struct{})
+	provider := &stubProductProvider{
+		getFn: func(ctx context.Context, sku uint64) (*model.Product, error) {
+			<-release
+			return &model.Product{Sku: sku}, nil
+		},
+	}
+
+	svc := NewCachingProductService(provider, 10, time.Minute, time.Minute)
+
+	const workers = 10
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			product, err := svc.GetProductBySku(context.Background(), 1)
+			require.NoError(t, err)
+			require.Equal(t, uint64(1), product.Sku)
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, int32(1), provider.calls.Load())
+}
+
+func TestCachingProductService_GetProductsBySkus(t *testing.T) {
+	provider := &stubProductProvider{
+		getFn: func(ctx context.Context, sku uint64) (*model.Product, error) {
+			if sku == 3 {
+				return nil, model.ErrProductNotFound
+			}
+			return &model.Product{Sku: sku, Name: "product"}, nil
+		},
+	}
+
+	svc := NewCachingProductService(provider, 10, time.Minute, time.Minute)
+
+	_, err := svc.GetProductBySku(context.Background(), 1)
+	require.NoError(t, err)
+
+	products, err := svc.GetProductsBySkus(context.Background(), []uint64{1, 2, 3, 2})
+	var lookupErr *model.ProductsLookupError
+	require.ErrorAs(t, err, &lookupErr)
+	require.ErrorIs(t, err, model.ErrProductNotFound)
+	require.Len(t, lookupErr.Errors, 1)
+	require.Len(t, products, 2)
+	require.Equal(t, [][]uint64{{2, 3}}, provider.batchSkus)
+
+	// Повторный запрос целиком обслуживается из кэша, включая ненайденный товар
+	products, err = svc.GetProductsBySkus(context.Background(), []uint64{1, 2, 3})
+	require.ErrorIs(t, err, model.ErrProductNotFound)
+	require.Len(t, products, 2)
+	require.Len(t, provider.batchSkus, 1)
+}
+
+func TestCachingProductService_MergesConcurrentBatchLookups(t *testing.T) {
+	release := make(chan struct{})
+	provider := &stubProductProvider{
+		getFn: func(ctx context.Context, sku uint64) (*model.Product, error) {
+			<-release
+			return &model.Product{Sku: sku}, nil
+		},
+	}
+
+	svc := NewCachingProductService(provider, 10, time.Minute, time.Minute)
+
+	const workers = 10
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Порядок товаров в запросе не важен для объединения
+			skus := []uint64{1, 2, 3}
+			if i%2 == 1 {
+				skus = []uint64{3, 1, 2}
+			}
+
+			products, err := svc.GetProductsBySkus(context.Background(), skus)
+			require.NoError(t, err)
+			require.Len(t, products, 3)
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, [][]uint64{{1, 2, 3}}, provider.batchSkus)
+}
+
+func TestCachingProductService_MergesOverlappingBatchLookups(t *testing.T) {
+	release := make(chan struct{})
+	provider := &stubProductProvider{
+		getFn: func(ctx context.Context, sku uint64) (*model.Product, error) {
+			<-release
+			return &model.Product{Sku: sku}, nil
+		},
+	}
+
+	svc := NewCachingProductService(provider, 10, time.Minute, time.Minute)
+
+	var wg sync.WaitGroup
+	lookup := func(skus []uint64) {
+		defer wg.Done()
+
+		products, err := svc.GetProductsBySkus(context.Background(), skus)
+		require.NoError(t, err)
+		require.Len(t, products, len(skus))
+	}
+
+	wg.Add(1)
+	go lookup([]uint64{1, 2})
+
+	require.Eventually(t, func() bool {
+		provider.mutex.Lock()
+		defer provider.mutex.Unlock()
+
+		return len(provider.batchSkus) == 1
+	}, time.Second, time.Millisecond)
+
+	// Товар 2 уже загружается, поэтому второй запрос догружает только товар 3
+	wg.Add(1)
+	go lookup([]uint64{2, 3})
+
+	require.Eventually(t, func() bool {
+		provider.mutex.Lock()
+		defer provider.mutex.Unlock()
+
+		return len(provider.batchSkus) == 2
+	}, time.Second, time.Millisecond)
+
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, [][]uint64{{1, 2}, {3}}, provider.batchSkus)
+}
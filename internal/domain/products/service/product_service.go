@@ -1,31 +1,76 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"golang.org/x/sync/errgroup"
 )
 
-const HeaderXApiKey = "X-API-KEY"
+const (
+	HeaderXApiKey = "X-API-KEY"
+
+	defaultLookupConcurrency = 8
+	maxProductsBatchSize     = 100
+
+	// batchReprobeInterval через столько пакетный эндпоинт запрашивается снова после 404 или 405
+	batchReprobeInterval = 5 * time.Minute
+)
+
+var (
+	errBatchLookupUnsupported = errors.New("batch lookup is not supported")
+	errBatchLookupUnavailable = errors.New("batch lookup is unavailable")
+)
 
 type ProductService struct {
-	client  http.Client
-	token   string
-	address string
+	client            http.Client
+	token             string
+	address           string
+	lookupConcurrency int
+
+	// batchSupported сбрасывается, если сервис товаров ответил 501 на пакетный запрос
+	batchSupported atomic.Bool
+	// batchPausedUntil время (unix nano), до которого пакетный эндпоинт не запрашивается после 404 или 405:
+	// такой ответ возможен во время выкатки сервиса товаров, поэтому эндпоинт не отключается навсегда
+	batchPausedUntil atomic.Int64
+	now              func() time.Time
+}
+
+type getProductsRequest struct {
+	Skus []uint64 `json:"skus"`
+}
+
+type getProductsResponse struct {
+	Products []model.Product `json:"products"`
 }
 
 func NewProductService(client http.Client,
 	token string,
-	address string) *ProductService {
-	return &ProductService{
-		client:  client,
-		token:   token,
-		address: address,
+	address string,
+	batchLookup bool,
+	lookupConcurrency int) *ProductService {
+	if lookupConcurrency <= 0 {
+		lookupConcurrency = defaultLookupConcurrency
 	}
+
+	s := &ProductService{
+		client:            client,
+		token:             token,
+		address:           address,
+		lookupConcurrency: lookupConcurrency,
+		now:               time.Now,
+	}
+	s.batchSupported.Store(batchLookup)
+
+	return s
 }
 
 func (s *ProductService) GetProductBySku(ctx context.Context, sku uint64) (*model.Product, error) {
@@ -42,7 +87,11 @@ func (s *ProductService) GetProductBySku(ctx context.Context, sku uint64) (*mode
 
 	response, err := s.client.Do(request)
 	if err != nil {
-		return nil, fmt.Errorf("client.Do: %w", err)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("client.Do: %w", err)
+		}
+
+		return nil, fmt.Errorf("%w: client.Do: %w", model.ErrProductServiceUnavailable, err)
 	}
 	defer response.Body.Close()
 
@@ -50,8 +99,12 @@ func (s *ProductService) GetProductBySku(ctx context.Context, sku uint64) (*mode
 		return nil, model.ErrProductNotFound
 	}
 
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: status %d", model.ErrProductServiceUnavailable, response.StatusCode)
+	}
+
 	if response.StatusCode != http.StatusOK {
-		return nil, errors.New("http query failed")
+		return nil, fmt.Errorf("http query failed: status %d", response.StatusCode)
 	}
 
 	product := &model.Product{}
@@ -61,3 +114,158 @@ func (s *ProductService) GetProductBySku(ctx context.Context, sku uint64) (*mode
 
 	return product, nil
 }
+
+// GetProductsBySkus возвращает найденные товары по sku. Если часть товаров получить не удалось,
+// вместе с найденными товарами возвращается *model.ProductsLookupError.
+func (s *ProductService) GetProductsBySkus(ctx context.Context, skus []uint64) (map[uint64]*model.Product, error) {
+	skus = uniqueSkus(skus)
+	if len(skus) == 0 {
+		return map[uint64]*model.Product{}, nil
+	}
+
+	if s.batchSupported.Load() && s.now().UnixNano() >= s.batchPausedUntil.Load() {
+		products, err := s.getProductsBatch(ctx, skus)
+		switch {
+		case errors.Is(err, errBatchLookupUnsupported):
+			s.batchSupported.Store(false)
+		case errors.Is(err, errBatchLookupUnavailable):
+			s.batchPausedUntil.Store(s.now().Add(batchReprobeInterval).UnixNano())
+		default:
+			return products, err
+		}
+	}
+
+	return s.getProductsFanOut(ctx, skus)
+}
+
+func (s *ProductService) getProductsBatch(ctx context.Context, skus []uint64) (map[uint64]*model.Product, error) {
+	products := make(map[uint64]*model.Product, len(skus))
+
+	for start := 0; start < len(skus); start += maxProductsBatchSize {
+		end := min(start+maxProductsBatchSize, len(skus))
+
+		chunk, err := s.getProductsChunk(ctx, skus[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range chunk {
+			products[chunk[i].Sku] = &chunk[i]
+		}
+	}
+
+	lookupErrors := make(map[uint64]error)
+	for _, sku := range skus {
+		if _, ok := products[sku]; !ok {
+			lookupErrors[sku] = model.ErrProductNotFound
+		}
+	}
+
+	if len(lookupErrors) > 0 {
+		return products, &model.ProductsLookupError{Errors: lookupErrors}
+	}
+
+	return products, nil
+}
+
+func (s *ProductService) getProductsChunk(ctx context.Context, skus []uint64) ([]model.Product, error) {
+	body, err := json.Marshal(getProductsRequest{Skus: skus})
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("%s/product/list", s.address),
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext: %w", err)
+	}
+
+	request.Header.Add(HeaderXApiKey, s.token)
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("client.Do: %w", err)
+		}
+
+		return nil, fmt.Errorf("%w: client.Do: %w", model.ErrProductServiceUnavailable, err)
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotImplemented:
+		return nil, errBatchLookupUnsupported
+	case response.StatusCode == http.StatusNotFound,
+		response.StatusCode == http.StatusMethodNotAllowed:
+		return nil, errBatchLookupUnavailable
+	case response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError:
+		return nil, fmt.Errorf("%w: status %d", model.ErrProductServiceUnavailable, response.StatusCode)
+	case response.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("http query failed: status %d", response.StatusCode)
+	}
+
+	result := getProductsResponse{}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return result.Products, nil
+}
+
+// getProductsFanOut запрашивает товары по одному с ограниченной параллельностью.
+func (s *ProductService) getProductsFanOut(ctx context.Context, skus []uint64) (map[uint64]*model.Product, error) {
+	var (
+		mu           sync.Mutex
+		products     = make(map[uint64]*model.Product, len(skus))
+		lookupErrors = make(map[uint64]error)
+	)
+
+	var g errgroup.Group
+	g.SetLimit(s.lookupConcurrency)
+	for _, sku := range skus {
+		g.Go(func() error {
+			product, err := s.GetProductBySku(ctx, sku)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				lookupErrors[sku] = err
+			} else {
+				products[sku] = product
+			}
+
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(lookupErrors) > 0 {
+		return products, &model.ProductsLookupError{Errors: lookupErrors}
+	}
+
+	return products, nil
+}
+
+func uniqueSkus(skus []uint64) []uint64 {
+	seen := make(map[uint64]struct{}, len(skus))
+	result := make([]uint64, 0, len(skus))
+	for _, sku := range skus {
+		if _, ok := seen[sku]; ok {
+			continue
+		}
+
+		seen[sku] = struct{}{}
+		result = append(result, sku)
+	}
+
+	return result
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/http/round_trippers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductService_GetProductBySku_Unavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tr := round_trippers.NewCircuitBreakerRoundTripper(http.DefaultTransport, 1, time.Minute)
+	svc := NewProductService(http.Client{Transport: tr}, "token", server.URL, false, 0)
+
+	_, err := svc.GetProductBySku(context.Background(), 1)
+	require.ErrorIs(t, err, model.ErrProductServiceUnavailable)
+
+	// Цепь разомкнута, запрос завершается сразу с той же ошибкой
+	_, err = svc.GetProductBySku(context.Background(), 1)
+	require.ErrorIs(t, err, model.ErrProductServiceUnavailable)
+	require.ErrorIs(t, err, round_trippers.ErrCircuitOpen)
+}
+
+func TestProductService_GetProductBySku_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	svc := NewProductService(http.Client{}, "token", server.URL, false, 0)
+
+	_, err := svc.GetProductBySku(context.Background(), 1)
+	require.ErrorIs(t, err, model.ErrProductNotFound)
+}
+
+func TestProductService_GetProductsBySkus_Batch(t *testing.T) {
+	var batchCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/product/list", r.URL.Path)
+		batchCalls.Add(1)
+
+		request := getProductsRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		require.Equal(t, []uint64{1, 2, 3}, request.Skus)
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"products": []map[string]any{
+				{"sku": 1, "name": "first", "price": 10},
+				{"sku": 3, "name": "third", "price": 30},
+			},
+		})
+	}))
+	defer server.Close()
+
+	svc := NewProductService(http.Client{}, "token", server.URL, true, 0)
+
+	products, err := svc.GetProductsBySkus(context.Background(), []uint64{1, 2, 3, 1})
+	var lookupErr *model.ProductsLookupError
+	require.ErrorAs(t, err, &lookupErr)
+	require.Equal(t, map[uint64]error{2: model.ErrProductNotFound}, lookupErr.Errors)
+	require.Len(t, products, 2)
+	require.Equal(t, "third", products[3].Name)
+	require.Equal(t, int32(1), batchCalls.Load())
+}
+
+func TestProductService_GetProductsBySkus_FallbackToFanOut(t *testing.T) {
+	var batchCalls, singleCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/product/list" {
+			batchCalls.Add(1)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		singleCalls.Add(1)
+		if r.URL.Path == "/product/2" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var sku uint64
+		_, _ = fmt.Sscanf(r.URL.Path, "/product/%d", &sku)
+		_ = json.NewEncoder(w).Encode(map[string]any{"sku": sku, "name": "product", "price": 1})
+	}))
+	defer server.Close()
+
+	svc := NewProductService(http.Client{}, "token", server.URL, true, 2)
+
+	for i := 0; i < 2; i++ {
+		products, err := svc.GetProductsBySkus(context.Background(), []uint64{1, 2, 3})
+		var lookupErr *model.ProductsLookupError
+		require.ErrorAs(t, err, &lookupErr)
+		require.ErrorIs(t, lookupErr.Errors[2], model.ErrProductServiceUnavailable)
+		require.Len(t, products, 2)
+		require.Equal(t, uint64(3), products[3].Sku)
+	}
+
+	// До истечения паузы пакетный эндпоинт повторно не запрашивается
+	require.Equal(t, int32(1), batchCalls.Load())
+	require.Equal(t, int32(6), singleCalls.Load())
+}
+
+func TestProductService_GetProductsBySkus_ReprobesBatch(t *testing.T) {
+	var batchCalls, singleCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/product/list" {
+			singleCalls.Add(1)
+			_ = json.NewEncoder(w).Encode(map[string]any{"sku": 1, "name": "product", "price": 1})
+			return
+		}
+
+		if batchCalls.Add(1) == 1 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"products": []map[string]any{{"sku": 1, "name": "product", "price": 1}}})
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	svc := NewProductService(http.Client{}, "token", server.URL, true, 2)
+	svc.now = func() time.Time { return now }
+
+	_, err := svc.GetProductsBySkus(context.Background(), []uint64{1})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), singleCalls.Load())
+
+	// После паузы пакетный эндпоинт снова пробуется и, раз он появился, используется
+	now = now.Add(batchReprobeInterval)
+
+	_, err = svc.GetProductsBySkus(context.Background(), []uint64{1})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), batchCalls.Load())
+	require.Equal(t, int32(1), singleCalls.Load())
+}
+
+func TestProductService_GetProductsBySkus_NotImplementedDisablesBatch(t *testing.T) {
+	var batchCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/product/list" {
+			batchCalls.Add(1)
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"sku": 1, "name": "product", "price": 1})
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	svc := NewProductService(http.Client{}, "token", server.URL, true, 2)
+	svc.now = func() time.Time { return now }
+
+	_, err := svc.GetProductsBySkus(context.Background(), []uint64{1})
+	require.NoError(t, err)
+
+	now = now.Add(batchReprobeInterval)
+
+	_, err = svc.GetProductsBySkus(context.Background(), []uint64{1})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), batchCalls.Load())
+}
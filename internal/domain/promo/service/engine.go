@@ -0,0 +1,223 @@
+package service
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"gopkg.in/yaml.v3"
+)
+
+// Engine считает скидки по промокодам. Правила задаются один раз при создании и дальше не меняются,
+// поэтому Engine безопасен для конкурентного использования.
+type Engine struct {
+	codes map[string]model.PromoCode
+}
+
+type rulesFile struct {
+	Codes []promoCodeYaml `yaml:"codes"`
+}
+
+type promoCodeYaml struct {
+	Code  string          `yaml:"code"`
+	Rules []promoRuleYaml `yaml:"rules"`
+}
+
+type promoRuleYaml struct {
+	Type      model.PromoRuleType `yaml:"type"`
+	Percent   float64             `yaml:"percent"`
+	Amount    float64             `yaml:"amount"`
+	Sku       uint64              `yaml:"sku"`
+	BuyCount  uint32              `yaml:"buy"`
+	FreeCount uint32              `yaml:"free"`
+}
+
+func NewEngine(codes []model.PromoCode) (*Engine, error) {
+	e := &Engine{codes: make(map[string]model.PromoCode, len(codes))}
+
+	for _, code := range codes {
+		code.Code = model.NormalizePromoCode(code.Code)
+		if code.Code == "" {
+			return nil, fmt.Errorf("promo code must be not empty")
+		}
+
+		if _, ok := e.codes[code.Code]; ok {
+			return nil, fmt.Errorf("promo code %q is duplicated", code.Code)
+		}
+
+		for _, rule := range code.Rules {
+			if err := validateRule(rule); err != nil {
+				return nil, fmt.Errorf("promo code %q: %w", code.Code, err)
+			}
+		}
+
+		e.codes[code.Code] = code
+	}
+
+	return e, nil
+}
+
+// ParseEngine создает Engine по описанию промокодов в YAML
+func ParseEngine(data []byte) (*Engine, error) {
+	file := rulesFile{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("yaml.Unmarshal: %w", err)
+	}
+
+	codes := make([]model.PromoCode, 0, len(file.Codes))
+	for _, code := range file.Codes {
+		rules := make([]model.PromoRule, 0, len(code.Rules))
+		for _, rule := range code.Rules {
+			rules = append(rules, model.PromoRule{
+				Type:      rule.Type,
+				Percent:   rule.Percent,
+				Amount:    rule.Amount,
+				SkuId:     rule.Sku,
+				BuyCount:  rule.BuyCount,
+				FreeCount: rule.FreeCount,
+			})
+		}
+
+		codes = append(codes, model.PromoCode{Code: code.Code, Rules: rules})
+	}
+
+	return NewEngine(codes)
+}
+
+func LoadEngine(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	return ParseEngine(data)
+}
+
+// Apply возвращает скидки, которые промокод дает на позиции корзины.
+// Если условия промокода не выполнены, возвращается ошибка model.ErrPromoCodeNotApplicable.
+func (e *Engine) Apply(code string, lines []model.CartLine) ([]model.CartDiscount, error) {
+	promoCode, ok := e.codes[model.NormalizePromoCode(code)]
+	if !ok {
+		return nil, model.ErrPromoCodeNotFound
+	}
+
+	var subtotal float64
+	for _, line := range lines {
+		subtotal += line.TotalPrice
+	}
+
+	for _, rule := range promoCode.Rules {
+		if rule.Type == model.PromoRuleMinCartTotal && subtotal < rule.Amount {
+			return nil, model.NewPromoCodeNotApplicableError(rule.Amount, subtotal)
+		}
+	}
+
+	// Сначала скидки на отдельные товары, затем на корзину: процент считается от суммы после бесплатных единиц,
+	// а фиксированная скидка применяется последней и не уводит итог ниже нуля
+	rules := slices.Clone(promoCode.Rules)
+	slices.SortStableFunc(rules, func(a, b model.PromoRule) int {
+		return cmp.Compare(rulePriority(a), rulePriority(b))
+	})
+
+	remaining := subtotal
+	var discounts []model.CartDiscount
+	for _, rule := range rules {
+		amount := roundMoney(min(ruleDiscount(rule, lines, remaining), remaining))
+		if amount <= 0 {
+			continue
+		}
+
+		remaining -= amount
+		discounts = append(discounts, model.CartDiscount{
+			Code:   promoCode.Code,
+			Rule:   rule.Type,
+			SkuId:  rule.SkuId,
+			Amount: amount,
+		})
+	}
+
+	return discounts, nil
+}
+
+func ruleDiscount(rule model.PromoRule, lines []model.CartLine, remaining float64) float64 {
+	switch rule.Type {
+	case model.PromoRuleBuyXGetY:
+		line, ok := findLine(lines, rule.SkuId)
+		if !ok {
+			return 0
+		}
+
+		freeCount := line.Count / (rule.BuyCount + rule.FreeCount) * rule.FreeCount
+
+		return float64(freeCount) * line.Price
+	case model.PromoRulePercentOff:
+		base := remaining
+		if rule.SkuId != 0 {
+			line, ok := findLine(lines, rule.SkuId)
+			if !ok {
+				return 0
+			}
+
+			base = line.TotalPrice
+		}
+
+		return base * rule.Percent / 100
+	case model.PromoRuleAmountOff:
+		return rule.Amount
+	default:
+		return 0
+	}
+}
+
+func rulePriority(rule model.PromoRule) int {
+	switch rule.Type {
+	case model.PromoRuleBuyXGetY:
+		return 0
+	case model.PromoRulePercentOff:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func validateRule(rule model.PromoRule) error {
+	switch rule.Type {
+	case model.PromoRulePercentOff:
+		if rule.Percent <= 0 || rule.Percent > 100 {
+			return fmt.Errorf("percent_off: percent must be in (0, 100]")
+		}
+	case model.PromoRuleAmountOff:
+		if rule.Amount <= 0 {
+			return fmt.Errorf("amount_off: amount must be greater than zero")
+		}
+	case model.PromoRuleBuyXGetY:
+		if rule.SkuId == 0 || rule.BuyCount == 0 || rule.FreeCount == 0 {
+			return fmt.Errorf("buy_x_get_y: sku, buy and free must be greater than zero")
+		}
+	case model.PromoRuleMinCartTotal:
+		if rule.Amount <= 0 {
+			return fmt.Errorf("min_cart_total: amount must be greater than zero")
+		}
+	default:
+		return fmt.Errorf("unknown rule type %q", rule.Type)
+	}
+
+	return nil
+}
+
+func findLine(lines []model.CartLine, sku uint64) (model.CartLine, bool) {
+	for _, line := range lines {
+		if line.SkuId == sku {
+			return line, true
+		}
+	}
+
+	return model.CartLine{}, false
+}
+
+func roundMoney(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+const testRules = `
+codes:
+  - code: percent10
+    rules:
+      - type: percent_off
+        percent: 10
+  - code: MINUS500
+    rules:
+      - type: min_cart_total
+        amount: 3000
+      - type: amount_off
+        amount: 500
+  - code: TWOPLUSONE
+    rules:
+      - type: buy_x_get_y
+        sku: 1
+        buy: 2
+        free: 1
+  - code: COMBO
+    rules:
+      - type: amount_off
+        amount: 1000
+      - type: percent_off
+        percent: 50
+        sku: 2
+      - type: buy_x_get_y
+        sku: 1
+        buy: 1
+        free: 1
+`
+
+func testLines() []model.CartLine {
+	return []model.CartLine{
+		{CartItem: model.CartItem{SkuId: 1, Count: 7}, Price: 100, TotalPrice: 700},
+		{CartItem: model.CartItem{SkuId: 2, Count: 1}, Price: 250.5, TotalPrice: 250.5},
+	}
+}
+
+func TestEngine_Apply(t *testing.T) {
+	engine, err := ParseEngine([]byte(testRules))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		code      string
+		lines     []model.CartLine
+		discounts []model.CartDiscount
+	}{
+		{
+			name:  "percent off whole cart",
+			code:  " Percent10 ",
+			lines: testLines(),
+			discounts: []model.CartDiscount{
+				{Code: "PERCENT10", Rule: model.PromoRulePercentOff, Amount: 95.05},
+			},
+		},
+		{
+			name: "amount off with min cart total",
+			code: "MINUS500",
+			lines: []model.CartLine{
+				{CartItem: model.CartItem{SkuId: 1, Count: 30}, Price: 100, TotalPrice: 3000},
+			},
+			discounts: []model.CartDiscount{
+				{Code: "MINUS500", Rule: model.PromoRuleAmountOff, Amount: 500},
+			},
+		},
+		{
+			name:  "buy x get y",
+			code:  "TWOPLUSONE",
+			lines: testLines(),
+			discounts: []model.CartDiscount{
+				{Code: "TWOPLUSONE", Rule: model.PromoRuleBuyXGetY, SkuId: 1, Amount: 200},
+			},
+		},
+		{
+			name:  "buy x get y without sku",
+			code:  "TWOPLUSONE",
+			lines: testLines()[1:],
+		},
+		{
+			name:  "rules are applied by priority and capped by remaining total",
+			code:  "COMBO",
+			lines: testLines(),
+			discounts: []model.CartDiscount{
+				{Code: "COMBO", Rule: model.PromoRuleBuyXGetY, SkuId: 1, Amount: 300},
+				{Code: "COMBO", Rule: model.PromoRulePercentOff, SkuId: 2, Amount: 125.25},
+				{Code: "COMBO", Rule: model.PromoRuleAmountOff, Amount: 525.25},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			discounts, err := engine.Apply(tt.code, tt.lines)
+			require.NoError(t, err)
+			require.Equal(t, tt.discounts, discounts)
+		})
+	}
+}
+
+func TestEngine_Apply_MinCartTotalNotMet(t *testing.T) {
+	engine, err := ParseEngine([]byte(testRules))
+	require.NoError(t, err)
+
+	_, err = engine.Apply("MINUS500", testLines())
+	require.ErrorIs(t, err, model.ErrPromoCodeNotApplicable)
+
+	var domainErr *model.Error
+	require.ErrorAs(t, err, &domainErr)
+	require.Equal(t, 3000.0, domainErr.Details["min_cart_total"])
+	require.Equal(t, 950.5, domainErr.Details["subtotal"])
+}
+
+func TestEngine_Apply_UnknownCode(t *testing.T) {
+	engine, err := NewEngine(nil)
+	require.NoError(t, err)
+
+	_, err = engine.Apply("WELCOME10", testLines())
+	require.ErrorIs(t, err, model.ErrPromoCodeNotFound)
+}
+
+func TestNewEngine_InvalidRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		codes []model.PromoCode
+	}{
+		{name: "empty code", codes: []model.PromoCode{{Code: " "}}},
+		{name: "duplicated code", codes: []model.PromoCode{{Code: "A"}, {Code: "a"}}},
+		{name: "unknown type", codes: []model.PromoCode{{Code: "A", Rules: []model.PromoRule{{Type: "gift"}}}}},
+		{name: "percent above 100", codes: []model.PromoCode{{Code: "A", Rules: []model.PromoRule{{Type: model.PromoRulePercentOff, Percent: 120}}}}},
+		{name: "zero amount", codes: []model.PromoCode{{Code: "A", Rules: []model.PromoRule{{Type: model.PromoRuleAmountOff}}}}},
+		{name: "buy x get y without free", codes: []model.PromoCode{{Code: "A", Rules: []model.PromoRule{{Type: model.PromoRuleBuyXGetY, SkuId: 1, BuyCount: 2}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEngine(tt.codes)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestLoadEngine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "promo_codes.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testRules), 0o600))
+
+	engine, err := LoadEngine(path)
+	require.NoError(t, err)
+	require.Len(t, engine.codes, 4)
+
+	_, err = LoadEngine(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.Error(t, err)
+}
+
+func TestLoadEngine_ShippedRules(t *testing.T) {
+	_, err := LoadEngine(filepath.Join("..", "..", "..", "..", "configs", "promo_codes.yaml"))
+	require.NoError(t, err)
+}
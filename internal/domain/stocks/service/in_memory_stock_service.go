@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"sync"
+)
+
+// InMemoryStockService хранит остатки в памяти: для локального запуска и тестов без сервиса остатков
+type InMemoryStockService struct {
+	mu           sync.RWMutex
+	stocks       map[uint64]uint64
+	defaultCount uint64
+}
+
+// NewInMemoryStockService создает хранилище, в котором у товаров без явно заданного остатка доступно defaultCount единиц
+func NewInMemoryStockService(defaultCount uint64) *InMemoryStockService {
+	return &InMemoryStockService{
+		stocks:       make(map[uint64]uint64),
+		defaultCount: defaultCount,
+	}
+}
+
+func (s *InMemoryStockService) SetAvailableCount(sku uint64, count uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stocks[sku] = count
+}
+
+func (s *InMemoryStockService) GetAvailableCount(_ context.Context, sku uint64) (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count, ok := s.stocks[sku]
+	if !ok {
+		return s.defaultCount, nil
+	}
+
+	return count, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+)
+
+type StockService struct {
+	client  http.Client
+	address string
+}
+
+func NewStockService(client http.Client, address string) *StockService {
+	return &StockService{
+		client:  client,
+		address: address,
+	}
+}
+
+type stockInfoRequest struct {
+	Sku uint64 `json:"sku"`
+}
+
+type stockInfoResponse struct {
+	Count uint64 `json:"count"`
+}
+
+// GetAvailableCount возвращает доступный для покупки остаток товара. Неизвестный сервису остатков товар считается закончившимся.
+func (s *StockService) GetAvailableCount(ctx context.Context, sku uint64) (uint64, error) {
+	payload, err := json.Marshal(stockInfoRequest{Sku: sku})
+	if err != nil {
+		return 0, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("%s/stock/info", s.address),
+		bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("http.NewRequestWithContext: %w", err)
+	}
+
+	request.Header.Add("Content-Type", "application/json")
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, fmt.Errorf("client.Do: %w", err)
+		}
+
+		return 0, fmt.Errorf("%w: client.Do: %w", model.ErrStockServiceUnavailable, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return 0, nil
+	}
+
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError {
+		return 0, fmt.Errorf("%w: status %d", model.ErrStockServiceUnavailable, response.StatusCode)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("http query failed: status %d", response.StatusCode)
+	}
+
+	result := &stockInfoResponse{}
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		return 0, err
+	}
+
+	return result.Count, nil
+}
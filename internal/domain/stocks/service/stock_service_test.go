@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStockService_GetAvailableCount(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/stock/info", r.URL.Path)
+
+		request := stockInfoRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		if request.Sku == 404 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		require.NoError(t, json.NewEncoder(w).Encode(stockInfoResponse{Count: request.Sku * 10}))
+	}))
+	defer server.Close()
+
+	svc := NewStockService(http.Client{}, server.URL)
+
+	count, err := svc.GetAvailableCount(context.Background(), 3)
+	require.NoError(t, err)
+	require.Equal(t, uint64(30), count)
+
+	count, err = svc.GetAvailableCount(context.Background(), 404)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestStockService_GetAvailableCount_Unavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	svc := NewStockService(http.Client{}, server.URL)
+
+	_, err := svc.GetAvailableCount(context.Background(), 1)
+	require.ErrorIs(t, err, model.ErrStockServiceUnavailable)
+}
+
+func TestInMemoryStockService(t *testing.T) {
+	svc := NewInMemoryStockService(100)
+	svc.SetAvailableCount(1, 3)
+
+	count, err := svc.GetAvailableCount(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), count)
+
+	count, err = svc.GetAvailableCount(context.Background(), 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), count)
+}
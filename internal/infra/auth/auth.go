@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+)
+
+type claimsKey struct{}
+
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+}
+
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+// Verifier проверяет подпись, срок действия, издателя и аудиторию токена
+type Verifier struct {
+	key    any
+	parser *jwt.Parser
+}
+
+// NewVerifier для HS256 key является общим секретом, для RS256 — публичным ключом в формате PEM
+func NewVerifier(algorithm string, key []byte, issuer string, audience string) (*Verifier, error) {
+	if len(key) == 0 {
+		return nil, errors.New("auth key is empty")
+	}
+
+	verifier := &Verifier{}
+
+	switch algorithm {
+	case AlgorithmHS256:
+		verifier.key = key
+	case AlgorithmRS256:
+		publicKey, err := jwt.ParseRSAPublicKeyFromPEM(key)
+		if err != nil {
+			return nil, fmt.Errorf("jwt.ParseRSAPublicKeyFromPEM: %w", err)
+		}
+		verifier.key = publicKey
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{algorithm}),
+		jwt.WithExpirationRequired(),
+	}
+	if issuer != "" {
+		options = append(options, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		options = append(options, jwt.WithAudience(audience))
+	}
+	verifier.parser = jwt.NewParser(options...)
+
+	return verifier, nil
+}
+
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	claims := &Claims{}
+
+	_, err := v.parser.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+		return v.key, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parser.ParseWithClaims: %w", err)
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("token subject is empty")
+	}
+
+	return claims, nil
+}
+
+// LoadKey возвращает ключ из конфига, а если он не задан — читает его из файла
+func LoadKey(key string, keyFile string) ([]byte, error) {
+	if key != "" {
+		return []byte(key), nil
+	}
+
+	if keyFile == "" {
+		return nil, errors.New("neither key nor key_file is set")
+	}
+
+	content, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	return content, nil
+}
+
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+
+	return claims, ok
+}
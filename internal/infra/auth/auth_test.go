@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSecret   = "testSecret"
+	testIssuer   = "auth"
+	testAudience = "cart"
+)
+
+func newClaims(subject string) *Claims {
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{testAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, claims *Claims) string {
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	require.NoError(t, err)
+
+	return token
+}
+
+func TestVerifier_HS256(t *testing.T) {
+	verifier, err := NewVerifier(AlgorithmHS256, []byte(testSecret), testIssuer, testAudience)
+	require.NoError(t, err)
+
+	claims := newClaims("user")
+	claims.Roles = []string{"service"}
+
+	verified, err := verifier.Verify(sign(t, jwt.SigningMethodHS256, []byte(testSecret), claims))
+	require.NoError(t, err)
+	require.Equal(t, "user", verified.Subject)
+	require.True(t, verified.HasRole("service"))
+	require.False(t, verified.HasRole("admin"))
+}
+
+func TestVerifier_RS256(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(t, err)
+	publicKeyPem := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})
+
+	verifier, err := NewVerifier(AlgorithmRS256, publicKeyPem, testIssuer, testAudience)
+	require.NoError(t, err)
+
+	verified, err := verifier.Verify(sign(t, jwt.SigningMethodRS256, privateKey, newClaims("user")))
+	require.NoError(t, err)
+	require.Equal(t, "user", verified.Subject)
+
+	// Токен, подписанный общим секретом, не принимается при настроенном RS256
+	_, err = verifier.Verify(sign(t, jwt.SigningMethodHS256, publicKeyPem, newClaims("user")))
+	require.Error(t, err)
+}
+
+func TestVerifier_Rejects(t *testing.T) {
+	verifier, err := NewVerifier(AlgorithmHS256, []byte(testSecret), testIssuer, testAudience)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		key    []byte
+		modify func(claims *Claims)
+	}{
+		{
+			name: "wrong secret",
+			key:  []byte("otherSecret"),
+		},
+		{
+			name:   "expired",
+			modify: func(claims *Claims) { claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) },
+		},
+		{
+			name:   "without expiration",
+			modify: func(claims *Claims) { claims.ExpiresAt = nil },
+		},
+		{
+			name:   "wrong issuer",
+			modify: func(claims *Claims) { claims.Issuer = "other" },
+		},
+		{
+			name:   "wrong audience",
+			modify: func(claims *Claims) { claims.Audience = jwt.ClaimStrings{"loms"} },
+		},
+		{
+			name:   "empty subject",
+			modify: func(claims *Claims) { claims.Subject = "" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := []byte(testSecret)
+			if tt.key != nil {
+				key = tt.key
+			}
+
+			claims := newClaims("user")
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+
+			_, err := verifier.Verify(sign(t, jwt.SigningMethodHS256, key, claims))
+			require.Error(t, err)
+		})
+	}
+}
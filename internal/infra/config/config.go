@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -10,22 +11,161 @@ type Config struct {
 	Server struct {
 		Host string `yaml:"host"`
 		Port string `yaml:"port"`
+
+		ReadTimeout       time.Duration `yaml:"read_timeout"`
+		ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+		WriteTimeout      time.Duration `yaml:"write_timeout"`
+		IdleTimeout       time.Duration `yaml:"idle_timeout"`
+
+		// ShutdownDelay время между снятием готовности и остановкой приема соединений
+		ShutdownDelay   time.Duration `yaml:"shutdown_delay"`
+		ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
 	} `yaml:"server"`
 
+	Auth struct {
+		Enabled   bool   `yaml:"enabled"`
+		Algorithm string `yaml:"algorithm"`
+		// Key общий секрет для HS256 или публичный ключ PEM для RS256; если не задан, читается из KeyFile
+		Key         string `yaml:"key"`
+		KeyFile     string `yaml:"key_file"`
+		Issuer      string `yaml:"issuer"`
+		Audience    string `yaml:"audience"`
+		ServiceRole string `yaml:"service_role"`
+	} `yaml:"auth"`
+
+	RateLimit struct {
+		Enabled bool `yaml:"enabled"`
+		// IdleTtl время, после которого неиспользуемый бакет пользователя удаляется
+		IdleTtl time.Duration `yaml:"idle_ttl"`
+		Global  RateLimit     `yaml:"global"`
+		// Default лимит на пользователя для маршрутов, которых нет в Routes
+		Default RateLimit            `yaml:"default"`
+		Routes  map[string]RateLimit `yaml:"routes"`
+	} `yaml:"rate_limit"`
+
+	CartExpiration struct {
+		// Ttl время без изменений, после которого корзина удаляется; 0 отключает удаление
+		Ttl       time.Duration `yaml:"ttl"`
+		Interval  time.Duration `yaml:"interval"`
+		BatchSize int           `yaml:"batch_size"`
+	} `yaml:"cart_expiration"`
+
+	// CartLimits лимиты содержимого корзины; нулевое значение снимает ограничение
+	CartLimits struct {
+		MaxUnitsPerSku  uint32 `yaml:"max_units_per_sku"`
+		MaxDistinctSkus int    `yaml:"max_distinct_skus"`
+		MaxTotalUnits   uint64 `yaml:"max_total_units"`
+	} `yaml:"cart_limits"`
+
+	Promo struct {
+		// RulesFile YAML с правилами промокодов; без него ни один промокод не действует
+		RulesFile string `yaml:"rules_file"`
+	} `yaml:"promo"`
+
+	Idempotency struct {
+		Ttl             time.Duration `yaml:"ttl"`
+		LockTtl         time.Duration `yaml:"lock_ttl"`
+		CleanupInterval time.Duration `yaml:"cleanup_interval"`
+	} `yaml:"idempotency"`
+
+	Health struct {
+		CheckTimeout time.Duration `yaml:"check_timeout"`
+		// CacheTtl время, в течение которого повторные пробы получают сохраненный результат проверок
+		CacheTtl      time.Duration `yaml:"cache_ttl"`
+		CheckProducts bool          `yaml:"check_products"`
+	} `yaml:"health"`
+
+	Log struct {
+		Level  string `yaml:"level"`
+		Format string `yaml:"format"`
+	} `yaml:"log"`
+
+	Tracing struct {
+		Exporter    string `yaml:"exporter"`
+		Endpoint    string `yaml:"endpoint"`
+		ServiceName string `yaml:"service_name"`
+	} `yaml:"tracing"`
+
 	Products struct {
 		Host   string `yaml:"host"`
 		Port   string `yaml:"port"`
 		Token  string `yaml:"token"`
 		Schema string `yaml:"schema"`
+
+		CacheSize        int           `yaml:"cache_size"`
+		CacheTtl         time.Duration `yaml:"cache_ttl"`
+		CacheNotFoundTtl time.Duration `yaml:"cache_not_found_ttl"`
+
+		BatchLookup       bool `yaml:"batch_lookup"`
+		LookupConcurrency int  `yaml:"lookup_concurrency"`
+
+		Timeout time.Duration `yaml:"timeout"`
+
+		Retry struct {
+			MaxAttempts    int           `yaml:"max_attempts"`
+			BaseDelay      time.Duration `yaml:"base_delay"`
+			MaxDelay       time.Duration `yaml:"max_delay"`
+			AttemptTimeout time.Duration `yaml:"attempt_timeout"`
+		} `yaml:"retry"`
+
+		CircuitBreaker struct {
+			FailureThreshold int           `yaml:"failure_threshold"`
+			OpenTimeout      time.Duration `yaml:"open_timeout"`
+		} `yaml:"circuit_breaker"`
 	} `yaml:"products"`
 
+	Loms struct {
+		Host   string `yaml:"host"`
+		Port   string `yaml:"port"`
+		Schema string `yaml:"schema"`
+
+		Timeout time.Duration `yaml:"timeout"`
+	} `yaml:"loms"`
+
+	Stocks struct {
+		// Driver http (по умолчанию) или memory; memory не ходит в сервис остатков
+		Driver string `yaml:"driver"`
+		Host   string `yaml:"host"`
+		Port   string `yaml:"port"`
+		Schema string `yaml:"schema"`
+
+		// MemoryCount остаток каждого товара для драйвера memory
+		MemoryCount uint64 `yaml:"memory_count"`
+
+		Timeout time.Duration `yaml:"timeout"`
+	} `yaml:"stocks"`
+
 	Database struct {
+		Driver   string `yaml:"driver"`
 		User     string `yaml:"user"`
 		Password string `yaml:"password"`
 		Host     string `yaml:"host"`
 		Port     string `yaml:"port"`
 		Name     string `yaml:"name"`
 	} `yaml:"database"`
+
+	Kafka struct {
+		Host            string `yaml:"host"`
+		Port            string `yaml:"port"`
+		Brokers         string `yaml:"brokers"`
+		OrderTopic      string `yaml:"order_topic"`
+		CartTopic       string `yaml:"cart_topic"`
+		ConsumerGroupId string `yaml:"consumer_group_id"`
+	} `yaml:"kafka"`
+
+	Outbox struct {
+		PollInterval time.Duration `yaml:"poll_interval"`
+		BatchSize    int           `yaml:"batch_size"`
+		// Retention время хранения опубликованных сообщений; 0 отключает удаление
+		Retention       time.Duration `yaml:"retention"`
+		CleanupInterval time.Duration `yaml:"cleanup_interval"`
+	} `yaml:"outbox"`
+}
+
+// RateLimit параметры token bucket; Rps = 0 отключает ограничение
+type RateLimit struct {
+	Rps   float64 `yaml:"rps"`
+	Burst int     `yaml:"burst"`
 }
 
 func LoadConfig(filename string) (*Config, error) {
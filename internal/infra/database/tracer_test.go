@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/tracing/tracingtest"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestQueryTracer(t *testing.T) {
+	tracingtest.Install()
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	queryTracer := NewQueryTracer()
+
+	queryCtx := queryTracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{
+		SQL: "\nselect id from cart_items where user_id = $1",
+	})
+	queryTracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{Err: errors.New("timeout")})
+	parent.End()
+
+	span := tracingtest.SpanByName(parent.SpanContext().TraceID(), "postgres SELECT")
+	require.NotNil(t, span)
+	require.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	require.Contains(t, span.Attributes(), attribute.String("db.query.text", "select id from cart_items where user_id = $1"))
+	require.Equal(t, codes.Error, span.Status().Code)
+}
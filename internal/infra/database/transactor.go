@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type txKey struct{}
+
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type PgxTransactor struct {
+	pool *pgxpool.Pool
+}
+
+func NewPgxTransactor(pool *pgxpool.Pool) *PgxTransactor {
+	return &PgxTransactor{pool: pool}
+}
+
+// WithinTransaction выполняет fn в транзакции, доступной репозиториям через контекст.
+// Вложенный вызов открывает savepoint внутри уже начатой транзакции.
+func (t *PgxTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return pgx.BeginFunc(ctx, tx, func(nestedTx pgx.Tx) error {
+			return fn(context.WithValue(ctx, txKey{}, nestedTx))
+		})
+	}
+
+	return pgx.BeginTxFunc(ctx, t.pool, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// QuerierFromContext возвращает транзакцию из контекста, если она есть, иначе пул.
+func QuerierFromContext(ctx context.Context, pool *pgxpool.Pool) Querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+
+	return pool
+}
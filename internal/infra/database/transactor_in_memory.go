@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+	"sync"
+)
+
+type inMemoryTxKey struct{}
+
+// InMemoryTransactor сериализует транзакции для in-memory хранилищ.
+// Изменения при ошибке не откатываются.
+type InMemoryTransactor struct {
+	mutex sync.Mutex
+}
+
+func NewInMemoryTransactor() *InMemoryTransactor {
+	return &InMemoryTransactor{}
+}
+
+func (t *InMemoryTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(inMemoryTxKey{}) != nil {
+		return fn(ctx)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return fn(context.WithValue(ctx, inMemoryTxKey{}, struct{}{}))
+}
@@ -0,0 +1,53 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type checkerFunc struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// NewCheckerFunc оборачивает функцию в Checker
+func NewCheckerFunc(name string, check func(ctx context.Context) error) Checker {
+	return &checkerFunc{name: name, check: check}
+}
+
+func (c *checkerFunc) Name() string {
+	return c.name
+}
+
+func (c *checkerFunc) Check(ctx context.Context) error {
+	return c.check(ctx)
+}
+
+func NewPgxPoolChecker(name string, pool *pgxpool.Pool) Checker {
+	return NewCheckerFunc(name, pool.Ping)
+}
+
+// NewHttpChecker считает сервис доступным, если он ответил на запрос без ошибки 5xx
+func NewHttpChecker(name string, client http.Client, url string) Checker {
+	return NewCheckerFunc(name, func(ctx context.Context) error {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+		if err != nil {
+			return fmt.Errorf("http.NewRequestWithContext: %w", err)
+		}
+
+		response, err := client.Do(request)
+		if err != nil {
+			return fmt.Errorf("client.Do: %w", err)
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("unexpected status %d", response.StatusCode)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,141 @@
+package health
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	StatusOk   = "ok"
+	StatusFail = "fail"
+
+	defaultCheckTimeout = time.Second
+)
+
+// Checker проверяет доступность одной зависимости
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Name    string
+	Status  string
+	Latency time.Duration
+	Error   string
+}
+
+type Report struct {
+	Status string
+	Checks []CheckResult
+}
+
+// Registry выполняет зарегистрированные проверки параллельно и кэширует отчет на cacheTtl,
+// чтобы частые пробы не нагружали зависимости
+type Registry struct {
+	timeout  time.Duration
+	cacheTtl time.Duration
+	now      func() time.Time
+	group    singleflight.Group
+
+	mu       sync.Mutex
+	checkers []Checker
+	cached   *Report
+	cachedAt time.Time
+}
+
+func NewRegistry(timeout time.Duration, cacheTtl time.Duration) *Registry {
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+
+	return &Registry{
+		timeout:  timeout,
+		cacheTtl: cacheTtl,
+		now:      time.Now,
+	}
+}
+
+func (r *Registry) Register(checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checkers = append(r.checkers, checker)
+	r.cached = nil
+}
+
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.Lock()
+	if r.cached != nil && r.now().Sub(r.cachedAt) < r.cacheTtl {
+		report := *r.cached
+		r.mu.Unlock()
+
+		return report
+	}
+	checkers := slices.Clone(r.checkers)
+	r.mu.Unlock()
+
+	// Одновременные пробы склеиваются в один обход зависимостей. Проверки не зависят от отмены
+	// запроса, который их начал, и ограничены только таймаутом, поэтому ожидание тоже ограничено им
+	result := <-r.group.DoChan("check", func() (any, error) {
+		report := r.runChecks(context.WithoutCancel(ctx), checkers)
+
+		r.mu.Lock()
+		r.cached = &report
+		r.cachedAt = r.now()
+		r.mu.Unlock()
+
+		return report, nil
+	})
+
+	return result.Val.(Report)
+}
+
+func (r *Registry) runChecks(ctx context.Context, checkers []Checker) Report {
+	report := Report{
+		Status: StatusOk,
+		Checks: make([]CheckResult, len(checkers)),
+	}
+
+	var wg sync.WaitGroup
+	for i, checker := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = r.runCheck(ctx, checker)
+		}()
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		if check.Status != StatusOk {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+func (r *Registry) runCheck(ctx context.Context, checker Checker) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := checker.Check(ctx)
+
+	result := CheckResult{
+		Name:    checker.Name(),
+		Status:  StatusOk,
+		Latency: time.Since(start),
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,123 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Check(t *testing.T) {
+	registry := NewRegistry(time.Second, 0)
+	registry.Register(NewCheckerFunc("ok", func(context.Context) error { return nil }))
+	registry.Register(NewCheckerFunc("broken", func(context.Context) error { return errors.New("connection refused") }))
+
+	report := registry.Check(context.Background())
+
+	require.Equal(t, StatusFail, report.Status)
+	require.Len(t, report.Checks, 2)
+	require.Equal(t, "ok", report.Checks[0].Name)
+	require.Equal(t, StatusOk, report.Checks[0].Status)
+	require.Empty(t, report.Checks[0].Error)
+	require.Equal(t, "broken", report.Checks[1].Name)
+	require.Equal(t, StatusFail, report.Checks[1].Status)
+	require.Equal(t, "connection refused", report.Checks[1].Error)
+}
+
+func TestRegistry_CheckTimeout(t *testing.T) {
+	registry := NewRegistry(20*time.Millisecond, 0)
+	registry.Register(NewCheckerFunc("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+
+		return ctx.Err()
+	}))
+
+	report := registry.Check(context.Background())
+
+	require.Equal(t, StatusFail, report.Status)
+	require.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+}
+
+func TestRegistry_CheckCached(t *testing.T) {
+	now := time.Now()
+	var calls atomic.Int32
+
+	registry := NewRegistry(time.Second, time.Second)
+	registry.now = func() time.Time { return now }
+	registry.Register(NewCheckerFunc("counter", func(context.Context) error {
+		calls.Add(1)
+
+		return nil
+	}))
+
+	registry.Check(context.Background())
+	registry.Check(context.Background())
+	require.EqualValues(t, 1, calls.Load())
+
+	now = now.Add(time.Second)
+	registry.Check(context.Background())
+	require.EqualValues(t, 2, calls.Load())
+}
+
+func TestHttpChecker(t *testing.T) {
+	status := http.StatusNotFound
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	checker := NewHttpChecker("products", http.Client{}, server.URL)
+
+	// Любой ответ без 5xx означает, что сервис доступен
+	require.NoError(t, checker.Check(context.Background()))
+
+	status = http.StatusServiceUnavailable
+	require.Error(t, checker.Check(context.Background()))
+
+	server.Close()
+	require.Error(t, checker.Check(context.Background()))
+}
+
+func TestRegistry_CheckIgnoresCallerCancel(t *testing.T) {
+	registry := NewRegistry(time.Second, 0)
+	registry.Register(NewCheckerFunc("ok", func(ctx context.Context) error {
+		return ctx.Err()
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Отмена запроса пробы не должна превращаться в отказ зависимости
+	report := registry.Check(ctx)
+	require.Equal(t, StatusOk, report.Status)
+}
+
+func TestRegistry_CheckDoesNotBlockRegister(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	registry := NewRegistry(time.Second, 0)
+	registry.Register(NewCheckerFunc("slow", func(context.Context) error {
+		close(started)
+		<-release
+
+		return nil
+	}))
+
+	done := make(chan Report)
+	go func() { done <- registry.Check(context.Background()) }()
+	<-started
+
+	// Регистрация не ждет завершения идущих проверок
+	require.Eventually(t, func() bool {
+		registry.Register(NewCheckerFunc("ok", func(context.Context) error { return nil }))
+
+		return true
+	}, time.Second, time.Millisecond)
+
+	close(release)
+	require.Equal(t, StatusOk, (<-done).Status)
+}
@@ -0,0 +1,79 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/auth"
+	httpPkg "github.com/jva44ka/ozon-simulator-go-cart/pkg/http"
+)
+
+const bearerPrefix = "Bearer "
+
+// AuthMiddleware пропускает запрос, только если user_id из пути совпадает с subject токена.
+// Оборачивает конкретный маршрут, так как параметры пути появляются только после роутинга.
+type AuthMiddleware struct {
+	h           http.Handler
+	verifier    *auth.Verifier
+	serviceRole string
+}
+
+// NewAuthMiddleware владелец serviceRole может работать с корзиной любого пользователя; пустая роль отключает такой доступ
+func NewAuthMiddleware(h http.Handler, verifier *auth.Verifier, serviceRole string) http.Handler {
+	return &AuthMiddleware{
+		h:           h,
+		verifier:    verifier,
+		serviceRole: serviceRole,
+	}
+}
+
+func (m *AuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		m.unauthenticated(w, fmt.Errorf("%w: bearer token is missing", model.ErrUnauthenticated))
+		return
+	}
+
+	claims, err := m.verifier.Verify(header[len(bearerPrefix):])
+	if err != nil {
+		m.unauthenticated(w, fmt.Errorf("%w: %w", model.ErrUnauthenticated, err))
+		return
+	}
+
+	if !m.isServiceCall(claims) && !sameUser(claims.Subject, r.PathValue("user_id")) {
+		_ = httpPkg.WriteError(w, fmt.Errorf("%w: token subject %s", model.ErrForbidden, claims.Subject))
+		return
+	}
+
+	m.h.ServeHTTP(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
+}
+
+func (m *AuthMiddleware) isServiceCall(claims *auth.Claims) bool {
+	return m.serviceRole != "" && claims.HasRole(m.serviceRole)
+}
+
+func (m *AuthMiddleware) unauthenticated(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="cart"`)
+	_ = httpPkg.WriteError(w, err)
+}
+
+// sameUser сравнивает идентификаторы как uuid, чтобы регистр букв не влиял на результат
+func sameUser(subject string, userId string) bool {
+	if subject == userId {
+		return true
+	}
+
+	subjectUuid, err := uuid.Parse(subject)
+	if err != nil {
+		return false
+	}
+	userUuid, err := uuid.Parse(userId)
+	if err != nil {
+		return false
+	}
+
+	return subjectUuid == userUuid
+}
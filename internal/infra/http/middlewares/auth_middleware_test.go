@@ -0,0 +1,117 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/auth"
+	httpPkg "github.com/jva44ka/ozon-simulator-go-cart/pkg/http"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUserId     = "0b4e2b4e-5b1a-4c1e-9a55-3f0c5d7a9e11"
+	testAuthSecret = "testSecret"
+)
+
+func mintToken(t *testing.T, subject string, roles ...string) string {
+	claims := &auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Roles: roles,
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testAuthSecret))
+	require.NoError(t, err)
+
+	return token
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	verifier, err := auth.NewVerifier(auth.AlgorithmHS256, []byte(testAuthSecret), "", "")
+	require.NoError(t, err)
+
+	mx := http.NewServeMux()
+	mx.Handle("GET /user/{user_id}/cart", NewAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := auth.ClaimsFromContext(r.Context())
+		require.True(t, ok)
+		require.NotEmpty(t, claims.Subject)
+
+		w.WriteHeader(http.StatusOK)
+	}), verifier, "service"))
+
+	tests := []struct {
+		name          string
+		authorization string
+		statusCode    int
+		code          string
+	}{
+		{
+			name:          "owner",
+			authorization: "Bearer " + mintToken(t, testUserId),
+			statusCode:    http.StatusOK,
+		},
+		{
+			name:          "service role acts on any user",
+			authorization: "Bearer " + mintToken(t, "checkout-worker", "service"),
+			statusCode:    http.StatusOK,
+		},
+		{
+			name:          "missing token",
+			authorization: "",
+			statusCode:    http.StatusUnauthorized,
+			code:          "unauthenticated",
+		},
+		{
+			name:          "not a bearer token",
+			authorization: "Basic dXNlcjpwYXNz",
+			statusCode:    http.StatusUnauthorized,
+			code:          "unauthenticated",
+		},
+		{
+			name:          "invalid token",
+			authorization: "Bearer invalid",
+			statusCode:    http.StatusUnauthorized,
+			code:          "unauthenticated",
+		},
+		{
+			name:          "another user",
+			authorization: "Bearer " + mintToken(t, "7d8f8d3c-2c7a-4c36-8a4e-4a1c7f0b6d22"),
+			statusCode:    http.StatusForbidden,
+			code:          "forbidden",
+		},
+		{
+			name:          "role without service access",
+			authorization: "Bearer " + mintToken(t, "checkout-worker", "viewer"),
+			statusCode:    http.StatusForbidden,
+			code:          "forbidden",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/user/"+testUserId+"/cart", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			mx.ServeHTTP(w, r)
+			require.Equal(t, tt.statusCode, w.Code)
+
+			if tt.code == "" {
+				return
+			}
+
+			response := httpPkg.ErrorResponse{}
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+			require.Equal(t, tt.code, response.Code)
+		})
+	}
+}
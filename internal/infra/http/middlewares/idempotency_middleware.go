@@ -0,0 +1,227 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+	httpPkg "github.com/jva44ka/ozon-simulator-go-cart/pkg/http"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	maxIdempotentBodySize    = 1 << 20
+	defaultIdempotencyKeyTtl = 24 * time.Hour
+	defaultIdempotencyLock   = time.Minute
+)
+
+type IdempotencyStore interface {
+	Acquire(ctx context.Context, record model.IdempotencyRecord, now time.Time) (*model.IdempotencyRecord, error)
+	Complete(ctx context.Context, record model.IdempotencyRecord, lockedUntil time.Time) error
+	Release(ctx context.Context, userId uuid.UUID, key string, lockedUntil time.Time) error
+}
+
+// IdempotencyMiddleware сохраняет первый ответ на запрос с Idempotency-Key и отдает его при повторах.
+// Ключи уникальны в пределах пользователя. Ответы 5xx не сохраняются, чтобы повтор мог выполнить запрос заново.
+// Пока запрос выполняется, ключ занят только на lockTtl: если процесс упадет, не сохранив ответ,
+// повтор сможет занять ключ после истечения блокировки, а не через ttl.
+type IdempotencyMiddleware struct {
+	h       http.Handler
+	store   IdempotencyStore
+	ttl     time.Duration
+	lockTtl time.Duration
+	now     func() time.Time
+}
+
+func NewIdempotencyMiddleware(h http.Handler, store IdempotencyStore, ttl time.Duration, lockTtl time.Duration) http.Handler {
+	if ttl <= 0 {
+		ttl = defaultIdempotencyKeyTtl
+	}
+
+	if lockTtl <= 0 {
+		lockTtl = defaultIdempotencyLock
+	}
+
+	return &IdempotencyMiddleware{
+		h:       h,
+		store:   store,
+		ttl:     ttl,
+		lockTtl: lockTtl,
+		now:     time.Now,
+	}
+}
+
+func (m *IdempotencyMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get(HeaderIdempotencyKey)
+	if key == "" {
+		m.h.ServeHTTP(w, r)
+		return
+	}
+
+	// Некорректный user_id отклонит сам обработчик
+	userId, err := uuid.Parse(r.PathValue("user_id"))
+	if err != nil {
+		m.h.ServeHTTP(w, r)
+		return
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		_ = httpPkg.WriteError(w, model.NewValidationError(
+			HeaderIdempotencyKey,
+			fmt.Sprintf("idempotency key must not be longer than %d characters", maxIdempotencyKeyLength),
+		))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			_ = httpPkg.WriteError(w, model.NewValidationError(
+				"body",
+				fmt.Sprintf("request body must not be larger than %d bytes", maxBytesErr.Limit),
+			))
+			return
+		}
+
+		_ = httpPkg.WriteError(w, model.NewValidationError("body", "failed to read request body"))
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	now := m.now()
+	requestHash := hashRequest(r, body)
+
+	// Время истечения блокировки служит ее меткой: сохранить ответ или освободить ключ может только тот,
+	// кто его занял. Точность Postgres — микросекунды, поэтому время округляется заранее
+	lockedUntil := now.Add(m.lockTtl).Truncate(time.Microsecond)
+
+	existing, err := m.store.Acquire(r.Context(), model.IdempotencyRecord{
+		UserId:      userId,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   lockedUntil,
+	}, now)
+	if err != nil {
+		_ = httpPkg.WriteError(w, fmt.Errorf("store.Acquire: %w", err))
+		return
+	}
+
+	if existing != nil {
+		m.replay(w, existing, requestHash)
+		return
+	}
+
+	// Сохранение ответа не должно прерываться, если клиент уже отключился
+	storeCtx := context.WithoutCancel(r.Context())
+
+	rw := &recordingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+
+		if err := m.store.Release(storeCtx, userId, key, lockedUntil); err != nil {
+			logger.FromContext(r.Context()).Error("idempotency key release failed", "error", err)
+		}
+	}()
+
+	m.h.ServeHTTP(rw, r)
+
+	if rw.statusCode >= http.StatusInternalServerError {
+		return
+	}
+
+	err = m.store.Complete(storeCtx, model.IdempotencyRecord{
+		UserId:      userId,
+		Key:         key,
+		RequestHash: requestHash,
+		StatusCode:  rw.statusCode,
+		ContentType: rw.Header().Get("Content-Type"),
+		Body:        rw.body.Bytes(),
+		ExpiresAt:   m.now().Add(m.ttl),
+	}, lockedUntil)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("idempotency key complete failed", "error", err)
+		return
+	}
+
+	completed = true
+}
+
+func (m *IdempotencyMiddleware) replay(w http.ResponseWriter, record *model.IdempotencyRecord, requestHash string) {
+	if record.RequestHash != requestHash {
+		_ = httpPkg.WriteError(w, model.ErrIdempotencyKeyReused)
+		return
+	}
+
+	if !record.Completed() {
+		_ = httpPkg.WriteError(w, model.ErrIdempotencyKeyInProgress)
+		return
+	}
+
+	if record.ContentType != "" {
+		w.Header().Set("Content-Type", record.ContentType)
+	}
+	w.Header().Set(HeaderIdempotentReplayed, "true")
+	w.WriteHeader(record.StatusCode)
+	_, _ = w.Write(record.Body)
+}
+
+// hashRequest учитывает метод и путь, чтобы ключ нельзя было переиспользовать для другого товара
+func hashRequest(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method))
+	hash.Write([]byte{'\n'})
+	hash.Write([]byte(r.URL.Path))
+	hash.Write([]byte{'\n'})
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// recordingResponseWriter пишет ответ клиенту и одновременно сохраняет его копию
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *recordingResponseWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.statusCode = statusCode
+		w.wroteHeader = true
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *recordingResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingResponseWriter) SetError(err error) {
+	if recorder, ok := w.ResponseWriter.(interface{ SetError(err error) }); ok {
+		recorder.SetError(err)
+	}
+}
+
+func (w *recordingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
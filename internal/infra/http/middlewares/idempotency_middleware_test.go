@@ -0,0 +1,171 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/idempotency/repository"
+	httpPkg "github.com/jva44ka/ozon-simulator-go-cart/pkg/http"
+	"github.com/stretchr/testify/require"
+)
+
+func newIdempotentMux(t *testing.T, statusCode *int) (http.Handler, *int) {
+	t.Helper()
+
+	calls := 0
+	mx := http.NewServeMux()
+	mx.Handle("POST /user/{user_id}/cart/{sku_id}", NewIdempotencyMiddleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(*statusCode)
+			_, _ = w.Write([]byte(`{"calls":` + strconv.Itoa(calls) + `}`))
+		}),
+		repository.NewInMemoryIdempotencyRepository(),
+		0,
+		0,
+	))
+
+	return mx, &calls
+}
+
+func postWithKey(h http.Handler, sku string, key string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/user/"+testUserId+"/cart/"+sku, strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(HeaderIdempotencyKey, key)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestIdempotencyMiddleware_Replay(t *testing.T) {
+	statusCode := http.StatusOK
+	h, calls := newIdempotentMux(t, &statusCode)
+
+	first := postWithKey(h, "1", "key", `{"count":1}`)
+	require.Equal(t, http.StatusOK, first.Code)
+	require.Empty(t, first.Header().Get(HeaderIdempotentReplayed))
+
+	second := postWithKey(h, "1", "key", `{"count":1}`)
+	require.Equal(t, http.StatusOK, second.Code)
+	require.Equal(t, "true", second.Header().Get(HeaderIdempotentReplayed))
+	require.Equal(t, "application/json", second.Header().Get("Content-Type"))
+	require.Equal(t, first.Body.String(), second.Body.String())
+	require.Equal(t, 1, *calls)
+
+	// Без ключа запрос выполняется каждый раз
+	postWithKey(h, "1", "", `{"count":1}`)
+	require.Equal(t, 2, *calls)
+}
+
+func TestIdempotencyMiddleware_KeyReused(t *testing.T) {
+	statusCode := http.StatusOK
+	h, calls := newIdempotentMux(t, &statusCode)
+
+	require.Equal(t, http.StatusOK, postWithKey(h, "1", "key", `{"count":1}`).Code)
+
+	for _, tt := range []struct{ sku, body string }{
+		{"1", `{"count":2}`},
+		{"2", `{"count":1}`},
+	} {
+		w := postWithKey(h, tt.sku, "key", tt.body)
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		response := httpPkg.ErrorResponse{}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		require.Equal(t, "idempotency_key_reused", response.Code)
+	}
+
+	require.Equal(t, 1, *calls)
+}
+
+func TestIdempotencyMiddleware_ServerErrorIsNotStored(t *testing.T) {
+	statusCode := http.StatusServiceUnavailable
+	h, calls := newIdempotentMux(t, &statusCode)
+
+	require.Equal(t, http.StatusServiceUnavailable, postWithKey(h, "1", "key", `{"count":1}`).Code)
+
+	statusCode = http.StatusOK
+	w := postWithKey(h, "1", "key", `{"count":1}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, w.Header().Get(HeaderIdempotentReplayed))
+	require.Equal(t, 2, *calls)
+}
+
+func TestIdempotencyMiddleware_InProgress(t *testing.T) {
+	store := repository.NewInMemoryIdempotencyRepository()
+
+	var inner http.Handler
+	mx := http.NewServeMux()
+	mx.Handle("POST /user/{user_id}/cart/{sku_id}", NewIdempotencyMiddleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { inner.ServeHTTP(w, r) }),
+		store,
+		0,
+		0,
+	))
+
+	// Повтор, пришедший во время обработки первого запроса, получает 409
+	var nested *httptest.ResponseRecorder
+	inner = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		nested = postWithKey(mx, "1", "key", `{"count":1}`)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	require.Equal(t, http.StatusOK, postWithKey(mx, "1", "key", `{"count":1}`).Code)
+	require.Equal(t, http.StatusConflict, nested.Code)
+}
+
+func TestIdempotencyMiddleware_ExpiredLockTakeover(t *testing.T) {
+	now := time.Now()
+
+	var inner http.Handler
+	m := NewIdempotencyMiddleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { inner.ServeHTTP(w, r) }),
+		repository.NewInMemoryIdempotencyRepository(),
+		time.Hour,
+		time.Minute,
+	).(*IdempotencyMiddleware)
+	m.now = func() time.Time { return now }
+
+	mx := http.NewServeMux()
+	mx.Handle("POST /user/{user_id}/cart/{sku_id}", m)
+
+	// Первый запрос завис дольше блокировки, повтор выполняется заново, а не получает 409
+	var nested *httptest.ResponseRecorder
+	inner = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now = now.Add(2 * time.Minute)
+		inner = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("second"))
+		})
+		nested = postWithKey(mx, "1", "key", `{"count":1}`)
+		_, _ = w.Write([]byte("first"))
+	})
+
+	require.Equal(t, "first", postWithKey(mx, "1", "key", `{"count":1}`).Body.String())
+	require.Equal(t, http.StatusOK, nested.Code)
+	require.Equal(t, "second", nested.Body.String())
+
+	// Сохранен ответ запроса, который владеет ключом
+	replayed := postWithKey(mx, "1", "key", `{"count":1}`)
+	require.Equal(t, "true", replayed.Header().Get(HeaderIdempotentReplayed))
+	require.Equal(t, "second", replayed.Body.String())
+}
+
+func TestIdempotencyMiddleware_BodyTooLarge(t *testing.T) {
+	statusCode := http.StatusOK
+	h, calls := newIdempotentMux(t, &statusCode)
+
+	w := postWithKey(h, "1", "key", strings.Repeat("a", maxIdempotentBodySize+1))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Zero(t, *calls)
+}
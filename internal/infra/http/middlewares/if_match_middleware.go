@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	httpPkg "github.com/jva44ka/ozon-simulator-go-cart/pkg/http"
+)
+
+// IfMatchMiddleware передает версии корзины из If-Match в контекст. Сверка выполняется в сервисе
+// внутри транзакции изменения, поэтому проверка и запись не разделены гонкой.
+type IfMatchMiddleware struct {
+	h http.Handler
+}
+
+func NewIfMatchMiddleware(h http.Handler) http.Handler {
+	return &IfMatchMiddleware{h: h}
+}
+
+func (m *IfMatchMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		m.h.ServeHTTP(w, r)
+		return
+	}
+
+	// Для "*" подходит любая версия. Заголовок без распознанных тегов не совпадет ни с одной версией.
+	versions, matchAny := httpPkg.ParseETags(header, false)
+	if matchAny {
+		m.h.ServeHTTP(w, r)
+		return
+	}
+
+	m.h.ServeHTTP(w, r.WithContext(model.WithExpectedCartVersions(r.Context(), versions)))
+}
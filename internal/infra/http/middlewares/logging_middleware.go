@@ -0,0 +1,101 @@
+package middlewares
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+)
+
+const HeaderXRequestId = "X-Request-Id"
+
+type LoggingMiddleware struct {
+	h   http.Handler
+	log *slog.Logger
+}
+
+func NewLoggingMiddleware(h http.Handler, log *slog.Logger) http.Handler {
+	return &LoggingMiddleware{h: h, log: log}
+}
+
+func (m *LoggingMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	requestId := r.Header.Get(HeaderXRequestId)
+	if requestId == "" {
+		requestId = uuid.NewString()
+	}
+	w.Header().Set(HeaderXRequestId, requestId)
+
+	log := m.log.With("request_id", requestId)
+
+	ctx := logger.WithRequestId(r.Context(), requestId)
+	ctx = logger.WithContext(ctx, log)
+	r, rt := withRoute(r.WithContext(ctx))
+
+	rw := &statusResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	m.h.ServeHTTP(rw, r)
+
+	// Шаблон маршрута и параметры пути заполняются роутером уже после вызова обработчика
+	rt.capture(r)
+	attrs := []any{
+		"method", r.Method,
+		"route", rt.pattern,
+		"status", rw.statusCode,
+		"latency", time.Since(start),
+	}
+	if rt.userId != "" {
+		attrs = append(attrs, "user_id", rt.userId)
+	}
+	if rw.err != nil {
+		attrs = append(attrs, "error", rw.err)
+	}
+
+	level := slog.LevelInfo
+	switch {
+	case rw.statusCode >= http.StatusInternalServerError:
+		level = slog.LevelError
+	case rw.statusCode >= http.StatusBadRequest:
+		level = slog.LevelWarn
+	}
+
+	log.Log(ctx, level, "http request", attrs...)
+}
+
+// statusResponseWriter запоминает код ответа и ошибку, переданную обработчиком через SetError
+type statusResponseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	err         error
+}
+
+func (w *statusResponseWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.statusCode = statusCode
+		w.wroteHeader = true
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+
+	return w.ResponseWriter.Write(b)
+}
+
+// SetError пробрасывается во внешнюю обертку, чтобы ошибку увидели все middleware цепочки
+func (w *statusResponseWriter) SetError(err error) {
+	w.err = err
+
+	if recorder, ok := w.ResponseWriter.(interface{ SetError(err error) }); ok {
+		recorder.SetError(err)
+	}
+}
+
+func (w *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
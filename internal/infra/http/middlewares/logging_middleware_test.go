@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+	httpPkg "github.com/jva44ka/ozon-simulator-go-cart/pkg/http"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := slog.New(slog.NewJSONHandler(buf, nil))
+
+	var requestId string
+	mx := http.NewServeMux()
+	mx.HandleFunc("GET /user/{user_id}/cart", func(w http.ResponseWriter, r *http.Request) {
+		requestId = logger.RequestIdFromContext(r.Context())
+		_ = httpPkg.WriteError(w, errors.New("connection refused"))
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/user/11111111-1111-1111-1111-111111111111/cart", nil)
+	request.Header.Set(HeaderXRequestId, "request-1")
+	w := httptest.NewRecorder()
+
+	NewLoggingMiddleware(mx, log).ServeHTTP(w, request)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Equal(t, "request-1", w.Header().Get(HeaderXRequestId))
+	require.Equal(t, "request-1", requestId)
+
+	record := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "ERROR", record["level"])
+	require.Equal(t, "GET", record["method"])
+	require.Equal(t, "GET /user/{user_id}/cart", record["route"])
+	require.Equal(t, 500.0, record["status"])
+	require.Equal(t, "11111111-1111-1111-1111-111111111111", record["user_id"])
+	require.Equal(t, "request-1", record["request_id"])
+	require.Equal(t, "connection refused", record["error"])
+	require.Contains(t, record, "latency")
+}
+
+func TestLoggingMiddleware_GeneratesRequestId(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := slog.New(slog.NewJSONHandler(buf, nil))
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	w := httptest.NewRecorder()
+
+	NewLoggingMiddleware(h, log).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotEmpty(t, w.Header().Get(HeaderXRequestId))
+
+	record := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "INFO", record["level"])
+	require.Equal(t, w.Header().Get(HeaderXRequestId), record["request_id"])
+}
+
+func TestLoggingMiddleware_RouteBehindTracing(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := slog.New(slog.NewJSONHandler(buf, nil))
+
+	mx := http.NewServeMux()
+	mx.HandleFunc("GET /user/{user_id}/cart", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Tracing заменяет запрос через WithContext, маршрут все равно должен дойти до внешних middleware
+	h := NewLoggingMiddleware(NewMetricsMiddleware(NewTracingMiddleware(mx)), log)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/22222222-2222-2222-2222-222222222222/cart", nil))
+
+	record := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "GET /user/{user_id}/cart", record["route"])
+	require.Equal(t, "22222222-2222-2222-2222-222222222222", record["user_id"])
+}
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/metrics"
+)
+
+// unmatchedRoute подставляется вместо пустого шаблона, чтобы не плодить метки по произвольным путям
+const unmatchedRoute = "unmatched"
+
+type MetricsMiddleware struct {
+	h http.Handler
+}
+
+func NewMetricsMiddleware(h http.Handler) http.Handler {
+	return &MetricsMiddleware{h: h}
+}
+
+func (m *MetricsMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	r, rt := withRoute(r)
+
+	rw := &statusResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	m.h.ServeHTTP(rw, r)
+
+	rt.capture(r)
+	route := rt.pattern
+	if route == "" {
+		route = unmatchedRoute
+	}
+	status := strconv.Itoa(rw.statusCode)
+
+	metrics.HttpRequestsTotal.WithLabelValues(r.Method, route, status).Inc()
+	metrics.HttpRequestDuration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+}
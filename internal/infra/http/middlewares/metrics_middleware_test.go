@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	mx := http.NewServeMux()
+	mx.HandleFunc("DELETE /user/{user_id}/cart", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	h := NewMetricsMiddleware(mx)
+
+	requestsBefore := testutil.ToFloat64(metrics.HttpRequestsTotal.WithLabelValues("DELETE", "DELETE /user/{user_id}/cart", "404"))
+	unmatchedBefore := testutil.ToFloat64(metrics.HttpRequestsTotal.WithLabelValues("GET", unmatchedRoute, "404"))
+
+	for _, userId := range []string{"first", "second"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/user/"+userId+"/cart", nil))
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	// Запросы к разным пользователям попадают в один ряд по шаблону маршрута
+	require.Equal(t, requestsBefore+2, testutil.ToFloat64(metrics.HttpRequestsTotal.WithLabelValues("DELETE", "DELETE /user/{user_id}/cart", "404")))
+	require.Equal(t, unmatchedBefore+1, testutil.ToFloat64(metrics.HttpRequestsTotal.WithLabelValues("GET", unmatchedRoute, "404")))
+}
@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/metrics"
+	httpPkg "github.com/jva44ka/ozon-simulator-go-cart/pkg/http"
+	"github.com/jva44ka/ozon-simulator-go-cart/pkg/ratelimit"
+	"golang.org/x/time/rate"
+)
+
+const (
+	rateLimitScopeUser   = "user"
+	rateLimitScopeGlobal = "global"
+)
+
+// RateLimitMiddleware ограничивает частоту запросов к маршруту для каждого user_id и общий поток запросов.
+// Оборачивает конкретный маршрут, так как user_id появляется только после роутинга.
+type RateLimitMiddleware struct {
+	h             http.Handler
+	userLimiter   *ratelimit.KeyedLimiter
+	globalLimiter *rate.Limiter
+	now           func() time.Time
+}
+
+// NewRateLimitMiddleware любой из ограничителей может быть nil, тогда соответствующий лимит не применяется
+func NewRateLimitMiddleware(h http.Handler, userLimiter *ratelimit.KeyedLimiter, globalLimiter *rate.Limiter) http.Handler {
+	return &RateLimitMiddleware{
+		h:             h,
+		userLimiter:   userLimiter,
+		globalLimiter: globalLimiter,
+		now:           time.Now,
+	}
+}
+
+func (m *RateLimitMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	now := m.now()
+
+	type scopedReservation struct {
+		scope       string
+		reservation *rate.Reservation
+	}
+
+	reservations := make([]scopedReservation, 0, 2)
+	if m.userLimiter != nil {
+		reservations = append(reservations, scopedReservation{rateLimitScopeUser, m.userLimiter.Reserve(r.PathValue("user_id"), now)})
+	}
+	if m.globalLimiter != nil {
+		reservations = append(reservations, scopedReservation{rateLimitScopeGlobal, m.globalLimiter.ReserveN(now, 1)})
+	}
+
+	var (
+		retryAfter time.Duration
+		scope      string
+	)
+	for _, sr := range reservations {
+		if delay := sr.reservation.DelayFrom(now); delay > retryAfter {
+			retryAfter = delay
+			scope = sr.scope
+		}
+	}
+
+	if retryAfter == 0 {
+		m.h.ServeHTTP(w, r)
+		return
+	}
+
+	// Отклоненный запрос не должен расходовать токены ни одного из ограничителей
+	for _, sr := range reservations {
+		sr.reservation.CancelAt(now)
+	}
+
+	metrics.RateLimitedTotal.WithLabelValues(r.Pattern, scope).Inc()
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	_ = httpPkg.WriteError(w, fmt.Errorf("%w: %s limit exceeded", model.ErrRateLimited, scope))
+}
@@ -0,0 +1,69 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/metrics"
+	"github.com/jva44ka/ozon-simulator-go-cart/pkg/ratelimit"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+const rateLimitedRoute = "POST /user/{user_id}/cart/{sku_id}"
+
+func newRateLimitedMux(userLimiter *ratelimit.KeyedLimiter, globalLimiter *rate.Limiter, now time.Time) http.Handler {
+	m := NewRateLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), userLimiter, globalLimiter).(*RateLimitMiddleware)
+	m.now = func() time.Time { return now }
+
+	mx := http.NewServeMux()
+	mx.Handle(rateLimitedRoute, m)
+
+	return mx
+}
+
+func addToCart(h http.Handler, userId string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/user/"+userId+"/cart/1", nil))
+
+	return w
+}
+
+func TestRateLimitMiddleware_User(t *testing.T) {
+	h := newRateLimitedMux(ratelimit.NewKeyedLimiter(0.5, 2, time.Minute), nil, time.Now())
+
+	rejectedBefore := testutil.ToFloat64(metrics.RateLimitedTotal.WithLabelValues(rateLimitedRoute, rateLimitScopeUser))
+
+	require.Equal(t, http.StatusOK, addToCart(h, "first").Code)
+	require.Equal(t, http.StatusOK, addToCart(h, "first").Code)
+
+	w := addToCart(h, "first")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "2", w.Header().Get("Retry-After"))
+
+	// Лимит одного пользователя не влияет на других
+	require.Equal(t, http.StatusOK, addToCart(h, "second").Code)
+
+	require.Equal(t, rejectedBefore+1, testutil.ToFloat64(metrics.RateLimitedTotal.WithLabelValues(rateLimitedRoute, rateLimitScopeUser)))
+}
+
+func TestRateLimitMiddleware_Global(t *testing.T) {
+	now := time.Now()
+	globalLimiter := rate.NewLimiter(1, 1)
+	userLimiter := ratelimit.NewKeyedLimiter(1, 1, time.Minute)
+	h := newRateLimitedMux(userLimiter, globalLimiter, now)
+
+	require.Equal(t, http.StatusOK, addToCart(h, "first").Code)
+
+	w := addToCart(h, "second")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// Отклоненный глобальным лимитом запрос не расходует токен пользователя
+	require.Zero(t, userLimiter.Reserve("second", now).DelayFrom(now))
+}
@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+)
+
+type routeKey struct{}
+
+// route шаблон маршрута и user_id запроса. ServeMux заполняет их только в том экземпляре запроса,
+// который получил сам, поэтому middleware, заменившие запрос через WithContext, передают их наружу через route
+type route struct {
+	pattern string
+	userId  string
+}
+
+// withRoute возвращает общий для всей цепочки route, при необходимости добавляя его в контекст запроса
+func withRoute(r *http.Request) (*http.Request, *route) {
+	if rt, ok := r.Context().Value(routeKey{}).(*route); ok {
+		return r, rt
+	}
+
+	rt := &route{}
+
+	return r.WithContext(context.WithValue(r.Context(), routeKey{}, rt)), rt
+}
+
+// capture запоминает маршрут, если роутер заполнил его в r. Вызывается после обработки запроса.
+func (rt *route) capture(r *http.Request) {
+	if rt.pattern != "" || r.Pattern == "" {
+		return
+	}
+
+	rt.pattern = r.Pattern
+	rt.userId = r.PathValue("user_id")
+}
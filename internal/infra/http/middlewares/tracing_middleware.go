@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("github.com/jva44ka/ozon-simulator-go-cart/internal/infra/http/middlewares")
+
+type TracingMiddleware struct {
+	h http.Handler
+}
+
+func NewTracingMiddleware(h http.Handler) http.Handler {
+	return &TracingMiddleware{h: h}
+}
+
+func (m *TracingMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r, rt := withRoute(r)
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+	ctx, span := tracer.Start(ctx, fmt.Sprintf("HTTP %s", r.Method),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLPath(r.URL.Path),
+		),
+	)
+	defer span.End()
+
+	if requestId := logger.RequestIdFromContext(ctx); requestId != "" {
+		span.SetAttributes(semconv.HTTPRequestHeader("x-request-id", requestId))
+	}
+
+	// Логи обработчиков связываются с трейсом через trace_id
+	ctx = logger.WithContext(ctx, logger.FromContext(ctx).With("trace_id", span.SpanContext().TraceID().String()))
+	r = r.WithContext(ctx)
+
+	rw := &statusResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	m.h.ServeHTTP(rw, r)
+
+	rt.capture(r)
+	if rt.pattern != "" {
+		span.SetName(rt.pattern)
+		span.SetAttributes(semconv.HTTPRoute(rt.pattern))
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(rw.statusCode))
+	if rw.statusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(rw.statusCode))
+	}
+	if rw.err != nil {
+		span.RecordError(rw.err)
+	}
+}
@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/http/round_trippers"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/tracing/tracingtest"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingMiddleware_SpanTree(t *testing.T) {
+	tracingtest.Install()
+
+	var traceparent string
+	products := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer products.Close()
+
+	client := http.Client{Transport: round_trippers.NewTracingRoundTripper(http.DefaultTransport, "products")}
+
+	mx := http.NewServeMux()
+	mx.HandleFunc("GET /user/{user_id}/cart", func(w http.ResponseWriter, r *http.Request) {
+		request, err := http.NewRequestWithContext(r.Context(), http.MethodGet, products.URL, http.NoBody)
+		require.NoError(t, err)
+
+		response, err := client.Do(request)
+		require.NoError(t, err)
+		response.Body.Close()
+	})
+
+	// Входящий запрос продолжает трейс вызывающей стороны
+	const parentTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	request := httptest.NewRequest(http.MethodGet, "/user/11111111-1111-1111-1111-111111111111/cart", nil)
+	request.Header.Set("traceparent", parentTraceparent)
+
+	NewTracingMiddleware(mx).ServeHTTP(httptest.NewRecorder(), request)
+
+	traceId, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+
+	serverSpan := tracingtest.SpanByName(traceId, "GET /user/{user_id}/cart")
+	require.NotNil(t, serverSpan)
+	require.Equal(t, trace.SpanKindServer, serverSpan.SpanKind())
+	require.Equal(t, "00f067aa0ba902b7", serverSpan.Parent().SpanID().String())
+	require.Contains(t, serverSpan.Attributes(), attribute.String("http.route", "GET /user/{user_id}/cart"))
+	require.Contains(t, serverSpan.Attributes(), attribute.Int("http.response.status_code", http.StatusOK))
+
+	clientSpan := tracingtest.SpanByName(traceId, "products GET")
+	require.NotNil(t, clientSpan)
+	require.Equal(t, serverSpan.SpanContext().SpanID(), clientSpan.Parent().SpanID())
+
+	// Сервис товаров получает контекст клиентского спана
+	require.Equal(t, "00-"+traceId.String()+"-"+clientSpan.SpanContext().SpanID().String()+"-01", traceparent)
+}
@@ -0,0 +1,118 @@
+package round_trippers
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// CircuitBreakerRoundTripper перестает отправлять запросы после failureThreshold неудач подряд
+// и сразу возвращает ErrCircuitOpen. Через openTimeout пропускается один пробный запрос:
+// при его успехе цепь замыкается, при неудаче снова размыкается.
+type CircuitBreakerRoundTripper struct {
+	rt               http.RoundTripper
+	failureThreshold int
+	openTimeout      time.Duration
+
+	mutex    sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+
+	now func() time.Time
+}
+
+func NewCircuitBreakerRoundTripper(
+	rt http.RoundTripper,
+	failureThreshold int,
+	openTimeout time.Duration,
+) http.RoundTripper {
+	return &CircuitBreakerRoundTripper{
+		rt:               rt,
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		now:              time.Now,
+	}
+}
+
+func (trp *CircuitBreakerRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if err := trp.before(); err != nil {
+		return nil, err
+	}
+
+	response, err := trp.rt.RoundTrip(r)
+
+	// Запрос отменил сам клиент или истек его дедлайн: о состоянии сервиса это ничего не говорит
+	if r.Context().Err() != nil {
+		trp.skip()
+
+		return response, err
+	}
+
+	failed := err != nil ||
+		response.StatusCode == http.StatusTooManyRequests ||
+		response.StatusCode >= http.StatusInternalServerError
+	trp.after(failed)
+
+	return response, err
+}
+
+func (trp *CircuitBreakerRoundTripper) before() error {
+	trp.mutex.Lock()
+	defer trp.mutex.Unlock()
+
+	switch trp.state {
+	case circuitOpen:
+		if trp.now().Sub(trp.openedAt) < trp.openTimeout {
+			return ErrCircuitOpen
+		}
+
+		trp.state = circuitHalfOpen
+
+		return nil
+	case circuitHalfOpen:
+		// Пока пробный запрос не завершился, остальные не пропускаем
+		return ErrCircuitOpen
+	default:
+		return nil
+	}
+}
+
+func (trp *CircuitBreakerRoundTripper) after(failed bool) {
+	trp.mutex.Lock()
+	defer trp.mutex.Unlock()
+
+	if !failed {
+		trp.state = circuitClosed
+		trp.failures = 0
+
+		return
+	}
+
+	trp.failures++
+	if trp.state == circuitHalfOpen || trp.failures >= trp.failureThreshold {
+		trp.state = circuitOpen
+		trp.openedAt = trp.now()
+	}
+}
+
+// skip не учитывает результат запроса. Если это был пробный запрос, следующий запрос станет новым пробным.
+func (trp *CircuitBreakerRoundTripper) skip() {
+	trp.mutex.Lock()
+	defer trp.mutex.Unlock()
+
+	if trp.state == circuitHalfOpen {
+		trp.state = circuitOpen
+	}
+}
@@ -0,0 +1,111 @@
+package round_trippers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreakerRoundTripper(t *testing.T) {
+	var (
+		calls   atomic.Int32
+		healthy atomic.Bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	trp := NewCircuitBreakerRoundTripper(http.DefaultTransport, 2, 10*time.Second).(*CircuitBreakerRoundTripper)
+	trp.now = func() time.Time { return now }
+	client := http.Client{Transport: trp}
+
+	get := func() (*http.Response, error) {
+		response, err := client.Get(server.URL)
+		if err == nil {
+			response.Body.Close()
+		}
+		return response, err
+	}
+
+	// Две неудачи подряд размыкают цепь
+	for i := 0; i < 2; i++ {
+		response, err := get()
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, response.StatusCode)
+	}
+
+	_, err := get()
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.Equal(t, int32(2), calls.Load())
+
+	// После таймаута пробный запрос снова неудачен — цепь остается разомкнутой
+	now = now.Add(11 * time.Second)
+	_, err = get()
+	require.NoError(t, err)
+	require.Equal(t, int32(3), calls.Load())
+
+	_, err = get()
+	require.ErrorIs(t, err, ErrCircuitOpen)
+
+	// Успешный пробный запрос замыкает цепь
+	healthy.Store(true)
+	now = now.Add(11 * time.Second)
+	response, err := get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	response, err = get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, int32(5), calls.Load())
+}
+
+func TestCircuitBreakerRoundTripper_IgnoresCanceledRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	trp := NewCircuitBreakerRoundTripper(http.DefaultTransport, 1, 10*time.Second).(*CircuitBreakerRoundTripper)
+	trp.now = func() time.Time { return now }
+
+	get := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		r, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+
+		response, err := trp.RoundTrip(r)
+		if err == nil {
+			response.Body.Close()
+		}
+
+		return err
+	}
+
+	// Истекший дедлайн клиента не считается неудачей сервиса
+	for i := 0; i < 3; i++ {
+		err := get()
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+
+	// Отмененный пробный запрос не оставляет цепь в полуразомкнутом состоянии
+	trp.state = circuitOpen
+	trp.openedAt = now.Add(-11 * time.Second)
+	require.ErrorIs(t, get(), context.DeadlineExceeded)
+	require.ErrorIs(t, get(), context.DeadlineExceeded)
+}
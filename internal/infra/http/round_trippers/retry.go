@@ -0,0 +1,166 @@
+package round_trippers
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type RetryRoundTripper struct {
+	rt             http.RoundTripper
+	maxAttempts    int
+	baseDelay      time.Duration
+	maxDelay       time.Duration
+	attemptTimeout time.Duration
+
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func NewRetryRoundTripper(
+	rt http.RoundTripper,
+	maxAttempts int,
+	baseDelay time.Duration,
+	maxDelay time.Duration,
+	attemptTimeout time.Duration,
+) http.RoundTripper {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &RetryRoundTripper{
+		rt:             rt,
+		maxAttempts:    maxAttempts,
+		baseDelay:      baseDelay,
+		maxDelay:       maxDelay,
+		attemptTimeout: attemptTimeout,
+		sleep:          sleepContext,
+	}
+}
+
+// RoundTrip повторяет запрос при ошибках соединения, 5xx и 429 с экспоненциальной задержкой и джиттером.
+// Если сервер прислал Retry-After, ждем указанное им время; если оно больше maxDelay,
+// не повторяем запрос и возвращаем ответ сервера как есть.
+func (trp *RetryRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		response, err := trp.roundTripAttempt(r)
+
+		if attempt == trp.maxAttempts || !isRetryable(r, response, err) {
+			return response, err
+		}
+
+		delay := trp.backoff(attempt)
+		if response != nil {
+			if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+				if retryAfter > trp.maxDelay {
+					return response, nil
+				}
+
+				delay = retryAfter
+			}
+
+			_, _ = io.Copy(io.Discard, response.Body)
+			_ = response.Body.Close()
+		}
+
+		if err = trp.sleep(r.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (trp *RetryRoundTripper) roundTripAttempt(r *http.Request) (*http.Response, error) {
+	ctx, cancel := r.Context(), context.CancelFunc(func() {})
+	if trp.attemptTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, trp.attemptTimeout)
+	}
+
+	attemptRequest := r.Clone(ctx)
+	if r.Body != nil && r.Body != http.NoBody && r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		attemptRequest.Body = body
+	}
+
+	response, err := trp.rt.RoundTrip(attemptRequest)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// Таймаут попытки распространяется и на чтение тела ответа
+	response.Body = &cancelOnCloseBody{ReadCloser: response.Body, cancel: cancel}
+
+	return response, nil
+}
+
+func (trp *RetryRoundTripper) backoff(attempt int) time.Duration {
+	delay := trp.baseDelay << (attempt - 1)
+	if delay <= 0 || delay > trp.maxDelay {
+		delay = trp.maxDelay
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+
+	return rand.N(delay) + 1
+}
+
+func isRetryable(r *http.Request, response *http.Response, err error) bool {
+	// Запрос с телом, которое нельзя прочитать повторно, не повторяем
+	if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+		return false
+	}
+
+	if err != nil {
+		return r.Context().Err() == nil
+	}
+
+	return response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError
+}
+
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	defer b.cancel()
+
+	return b.ReadCloser.Close()
+}
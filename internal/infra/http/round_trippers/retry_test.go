@@ -0,0 +1,216 @@
+package round_trippers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRetryRoundTripper(maxAttempts int, attemptTimeout time.Duration) (*RetryRoundTripper, *[]time.Duration) {
+	var delays []time.Duration
+
+	trp := NewRetryRoundTripper(http.DefaultTransport, maxAttempts, 10*time.Millisecond, time.Second, attemptTimeout).(*RetryRoundTripper)
+	trp.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+
+	return trp, &delays
+}
+
+func TestRetryRoundTripper_RetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	trp, delays := newTestRetryRoundTripper(3, 0)
+	client := http.Client{Transport: trp}
+
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, "ok", string(body))
+	require.Equal(t, int32(3), calls.Load())
+	require.Len(t, *delays, 2)
+	for _, delay := range *delays {
+		require.Greater(t, delay, time.Duration(0))
+		require.LessOrEqual(t, delay, time.Second)
+	}
+}
+
+func TestRetryRoundTripper_GivesUpAfterMaxAttempts(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	trp, _ := newTestRetryRoundTripper(3, 0)
+	client := http.Client{Transport: trp}
+
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	require.Equal(t, http.StatusBadGateway, response.StatusCode)
+	require.Equal(t, int32(3), calls.Load())
+}
+
+func TestRetryRoundTripper_DoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	trp, _ := newTestRetryRoundTripper(3, 0)
+	client := http.Client{Transport: trp}
+
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	require.Equal(t, http.StatusNotFound, response.StatusCode)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestRetryRoundTripper_RespectsRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	trp, delays := newTestRetryRoundTripper(2, 0)
+	client := http.Client{Transport: trp}
+
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, []time.Duration{time.Second}, *delays)
+}
+
+func TestRetryRoundTripper_RetryAfterOverMaxDelay(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	trp, delays := newTestRetryRoundTripper(3, 0)
+	client := http.Client{Transport: trp}
+
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	// Сервер просит подождать дольше maxDelay, поэтому его ответ возвращается без повторов
+	require.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	require.Equal(t, "30", response.Header.Get("Retry-After"))
+	require.Equal(t, int32(1), calls.Load())
+	require.Empty(t, *delays)
+}
+
+func TestRetryRoundTripper_AttemptTimeout(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	trp, _ := newTestRetryRoundTripper(2, 50*time.Millisecond)
+	client := http.Client{Transport: trp}
+
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, int32(2), calls.Load())
+}
+
+func TestRetryRoundTripper_RetriesConnectionErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	trp, delays := newTestRetryRoundTripper(3, 0)
+	client := http.Client{Transport: trp}
+
+	_, err := client.Get(url)
+	require.Error(t, err)
+	require.Len(t, *delays, 2)
+}
+
+func TestRetryRoundTripper_ReplaysBody(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		require.Equal(t, "payload", string(body))
+
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	trp, _ := newTestRetryRoundTripper(2, 0)
+	client := http.Client{Transport: trp}
+
+	response, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, int32(2), calls.Load())
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	delay, ok := parseRetryAfter("3")
+	require.True(t, ok)
+	require.Equal(t, 3*time.Second, delay)
+
+	delay, ok = parseRetryAfter(time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	require.True(t, ok)
+	require.Equal(t, time.Duration(0), delay)
+
+	_, ok = parseRetryAfter("soon")
+	require.False(t, ok)
+}
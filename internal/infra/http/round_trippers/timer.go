@@ -1,23 +1,69 @@
 package round_trippers
 
 import (
-	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/metrics"
 )
 
+const HeaderXRequestId = "X-Request-Id"
+
+const statusError = "error"
+
 type TimerRoundTripper struct {
-	rt http.RoundTripper
+	rt     http.RoundTripper
+	client string
+	log    *slog.Logger
 }
 
-func NewTimerRoundTipper(rt http.RoundTripper) http.RoundTripper {
-	return &TimerRoundTripper{rt: rt}
+func NewTimerRoundTipper(rt http.RoundTripper, client string, log *slog.Logger) http.RoundTripper {
+	return &TimerRoundTripper{rt: rt, client: client, log: log.With("client", client)}
 }
 
+// RoundTrip логирует и замеряет исходящий запрос и передает дальше идентификатор входящего запроса
 func (trp *TimerRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
-	defer func(now time.Time) {
-		fmt.Printf("SENT %s spent %s", r.URL.String(), time.Since(now))
-	}(time.Now())
+	start := time.Now()
+	log := trp.log
+
+	if requestId := logger.RequestIdFromContext(r.Context()); requestId != "" {
+		log = log.With("request_id", requestId)
+
+		if r.Header.Get(HeaderXRequestId) == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set(HeaderXRequestId, requestId)
+		}
+	}
+
+	response, err := trp.rt.RoundTrip(r)
+
+	status := statusError
+	if err == nil {
+		status = strconv.Itoa(response.StatusCode)
+	}
+	metrics.ClientRequestsTotal.WithLabelValues(trp.client, r.Method, status).Inc()
+	metrics.ClientRequestDuration.WithLabelValues(trp.client, r.Method, status).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		log.WarnContext(r.Context(), "outbound request failed",
+			"method", r.Method,
+			"url", r.URL.String(),
+			"latency", time.Since(start),
+			"error", err,
+		)
+
+		return nil, err
+	}
+
+	log.DebugContext(r.Context(), "outbound request",
+		"method", r.Method,
+		"url", r.URL.String(),
+		"status", response.StatusCode,
+		"latency", time.Since(start),
+	)
 
-	return trp.rt.RoundTrip(r)
+	return response, nil
 }
@@ -0,0 +1,66 @@
+package round_trippers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/logger"
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimerRoundTripper_PropagatesRequestId(t *testing.T) {
+	var receivedRequestId string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedRequestId = r.Header.Get(HeaderXRequestId)
+	}))
+	defer server.Close()
+
+	buf := &bytes.Buffer{}
+	log := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := http.Client{Transport: NewTimerRoundTipper(http.DefaultTransport, "products", log)}
+
+	ctx := logger.WithRequestId(context.Background(), "request-1")
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, http.NoBody)
+	require.NoError(t, err)
+
+	response, err := client.Do(request)
+	require.NoError(t, err)
+	response.Body.Close()
+
+	require.Equal(t, "request-1", receivedRequestId)
+	require.Empty(t, request.Header.Get(HeaderXRequestId))
+
+	record := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "outbound request", record["msg"])
+	require.Equal(t, "request-1", record["request_id"])
+	require.Equal(t, 200.0, record["status"])
+}
+
+func TestTimerRoundTripper_CountsRequestsByStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	url := server.URL
+
+	log := slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+	client := http.Client{Transport: NewTimerRoundTipper(http.DefaultTransport, "metrics-test", log)}
+
+	response, err := client.Get(url)
+	require.NoError(t, err)
+	response.Body.Close()
+
+	server.Close()
+	_, err = client.Get(url)
+	require.Error(t, err)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.ClientRequestsTotal.WithLabelValues("metrics-test", http.MethodGet, "503")))
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.ClientRequestsTotal.WithLabelValues("metrics-test", http.MethodGet, statusError)))
+}
@@ -0,0 +1,55 @@
+package round_trippers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/infra/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("github.com/jva44ka/ozon-simulator-go-cart/internal/infra/http/round_trippers")
+
+type TracingRoundTripper struct {
+	rt     http.RoundTripper
+	client string
+}
+
+func NewTracingRoundTripper(rt http.RoundTripper, client string) http.RoundTripper {
+	return &TracingRoundTripper{rt: rt, client: client}
+}
+
+// RoundTrip создает клиентский спан и передает его контекст в заголовке traceparent
+func (trp *TracingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := tracer.Start(r.Context(), fmt.Sprintf("%s %s", trp.client, r.Method),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLFull(r.URL.String()),
+			semconv.ServicePeerName(trp.client),
+		),
+	)
+	defer span.End()
+
+	r = r.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	response, err := trp.rt.RoundTrip(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(response.StatusCode))
+	if response.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(response.StatusCode))
+	}
+
+	return response, nil
+}
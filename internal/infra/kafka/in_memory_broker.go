@@ -0,0 +1,44 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+)
+
+// maxTopicMessages ограничивает число хранимых сообщений в топике: при превышении отбрасываются самые старые
+const maxTopicMessages = 10000
+
+// InMemoryBroker подменяет Kafka в тестах и при локальном запуске без брокера.
+type InMemoryBroker struct {
+	mutex  sync.RWMutex
+	topics map[string][]model.OutboxMessage
+}
+
+func NewInMemoryBroker() *InMemoryBroker {
+	return &InMemoryBroker{topics: make(map[string][]model.OutboxMessage)}
+}
+
+func (b *InMemoryBroker) SendMessages(_ context.Context, topic string, messages []model.OutboxMessage) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	topicMessages := append(b.topics[topic], messages...)
+	if overflow := len(topicMessages) - maxTopicMessages; overflow > 0 {
+		topicMessages = append([]model.OutboxMessage(nil), topicMessages[overflow:]...)
+	}
+	b.topics[topic] = topicMessages
+
+	return nil
+}
+
+func (b *InMemoryBroker) Messages(topic string) []model.OutboxMessage {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	result := make([]model.OutboxMessage, len(b.topics[topic]))
+	copy(result, b.topics[topic])
+
+	return result
+}
@@ -0,0 +1,25 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryBroker_DropsOldestOverLimit(t *testing.T) {
+	broker := NewInMemoryBroker()
+
+	messages := make([]model.OutboxMessage, maxTopicMessages+5)
+	for i := range messages {
+		messages[i].Id = uint64(i + 1)
+	}
+
+	require.NoError(t, broker.SendMessages(context.Background(), "cart-events", messages))
+
+	stored := broker.Messages("cart-events")
+	require.Len(t, stored, maxTopicMessages)
+	require.Equal(t, uint64(6), stored[0].Id)
+	require.Equal(t, uint64(maxTopicMessages+5), stored[len(stored)-1].Id)
+}
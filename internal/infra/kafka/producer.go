@@ -0,0 +1,51 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/segmentio/kafka-go"
+)
+
+const HeaderEventType = "event_type"
+
+type Producer struct {
+	writer *kafka.Writer
+}
+
+func NewProducer(brokers string) *Producer {
+	return &Producer{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(strings.Split(brokers, ",")...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+		},
+	}
+}
+
+func (p *Producer) SendMessages(ctx context.Context, topic string, messages []model.OutboxMessage) error {
+	kafkaMessages := make([]kafka.Message, 0, len(messages))
+	for _, message := range messages {
+		kafkaMessages = append(kafkaMessages, kafka.Message{
+			Topic: topic,
+			Key:   []byte(message.Key),
+			Value: message.Payload,
+			Headers: []kafka.Header{
+				{Key: HeaderEventType, Value: []byte(message.EventType)},
+			},
+			Time: message.CreatedAt,
+		})
+	}
+
+	if err := p.writer.WriteMessages(ctx, kafkaMessages...); err != nil {
+		return fmt.Errorf("writer.WriteMessages: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Producer) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	log, err := New(buf, "warn", "json")
+	require.NoError(t, err)
+
+	log.Info("skipped")
+	log.Warn("written", "key", "value")
+
+	record := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "written", record["msg"])
+	require.Equal(t, "value", record["key"])
+
+	_, err = New(buf, "verbose", "json")
+	require.Error(t, err)
+
+	_, err = New(buf, "info", "xml")
+	require.Error(t, err)
+}
+
+func TestFromContext(t *testing.T) {
+	require.Equal(t, slog.Default(), FromContext(context.Background()))
+
+	log := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	ctx := WithRequestId(WithContext(context.Background(), log), "request-1")
+
+	require.Equal(t, log, FromContext(ctx))
+	require.Equal(t, "request-1", RequestIdFromContext(ctx))
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "cart"
+
+var (
+	HttpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Количество входящих HTTP запросов",
+	}, []string{"method", "route", "status"})
+
+	HttpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Время обработки входящих HTTP запросов",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	ClientRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "client_requests_total",
+		Help:      "Количество исходящих HTTP запросов, status=error для сетевых ошибок",
+	}, []string{"client", "method", "status"})
+
+	ClientRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "client_request_duration_seconds",
+		Help:      "Время выполнения исходящих HTTP запросов",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"client", "method", "status"})
+
+	CartItemsAddedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "items_added_total",
+		Help:      "Количество добавленных в корзины экземпляров товаров",
+	})
+
+	CartItemsRemovedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "items_removed_total",
+		Help:      "Количество позиций, удаленных из корзин",
+	})
+
+	CartsClearedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "carts_cleared_total",
+		Help:      "Количество очищенных корзин",
+	})
+
+	CheckoutsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "checkouts_total",
+		Help:      "Количество попыток оформления заказа по результату",
+	}, []string{"result"})
+
+	RateLimitedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Количество запросов, отклоненных ограничителем, scope=user|global",
+	}, []string{"route", "scope"})
+)
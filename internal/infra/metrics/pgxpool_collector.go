@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PgxPoolCollector снимает статистику пула соединений в момент опроса /metrics
+type PgxPoolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns        *prometheus.Desc
+	idleConns            *prometheus.Desc
+	totalConns           *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquireTotal         *prometheus.Desc
+	emptyAcquireTotal    *prometheus.Desc
+	emptyAcquireWaitTime *prometheus.Desc
+	canceledAcquireTotal *prometheus.Desc
+}
+
+func NewPgxPoolCollector(pool *pgxpool.Pool) *PgxPoolCollector {
+	newDesc := func(name string, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+
+	return &PgxPoolCollector{
+		pool:                 pool,
+		acquiredConns:        newDesc("acquired_conns", "Количество занятых соединений"),
+		idleConns:            newDesc("idle_conns", "Количество свободных соединений"),
+		totalConns:           newDesc("total_conns", "Общее количество соединений"),
+		maxConns:             newDesc("max_conns", "Максимальный размер пула"),
+		acquireTotal:         newDesc("acquire_total", "Количество выдач соединений из пула"),
+		emptyAcquireTotal:    newDesc("empty_acquire_total", "Количество выдач, которым пришлось ждать свободное соединение"),
+		emptyAcquireWaitTime: newDesc("empty_acquire_wait_seconds_total", "Суммарное время ожидания свободного соединения"),
+		canceledAcquireTotal: newDesc("canceled_acquire_total", "Количество выдач, отмененных по контексту"),
+	}
+}
+
+func (c *PgxPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireTotal
+	ch <- c.emptyAcquireTotal
+	ch <- c.emptyAcquireWaitTime
+	ch <- c.canceledAcquireTotal
+}
+
+func (c *PgxPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireTotal, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireTotal, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireWaitTime, prometheus.CounterValue, stat.EmptyAcquireWaitTime().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquireTotal, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CacheStatsFunc возвращает текущие попадания, промахи и размер кэша
+type CacheStatsFunc func() (hits uint64, misses uint64, size int)
+
+// ProductCacheCollector отдает статистику кэша товаров
+type ProductCacheCollector struct {
+	stats CacheStatsFunc
+
+	hits   *prometheus.Desc
+	misses *prometheus.Desc
+	size   *prometheus.Desc
+}
+
+func NewProductCacheCollector(stats CacheStatsFunc) *ProductCacheCollector {
+	return &ProductCacheCollector{
+		stats:  stats,
+		hits:   prometheus.NewDesc(prometheus.BuildFQName(namespace, "products_cache", "hits_total"), "Количество попаданий в кэш товаров", nil, nil),
+		misses: prometheus.NewDesc(prometheus.BuildFQName(namespace, "products_cache", "misses_total"), "Количество промахов кэша товаров", nil, nil),
+		size:   prometheus.NewDesc(prometheus.BuildFQName(namespace, "products_cache", "size"), "Количество записей в кэше товаров", nil, nil),
+	}
+}
+
+func (c *ProductCacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.size
+}
+
+func (c *ProductCacheCollector) Collect(ch chan<- prometheus.Metric) {
+	hits, misses, size := c.stats()
+
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(misses))
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(size))
+}
@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOtlp   = "otlp"
+)
+
+// NewTracerProvider создает провайдер с экспортером из конфига и делает его глобальным.
+// С экспортером none спаны создаются и контекст передается дальше, но никуда не выгружаются.
+func NewTracerProvider(
+	ctx context.Context,
+	exporter string,
+	endpoint string,
+	serviceName string,
+	stdout io.Writer,
+) (*sdktrace.TracerProvider, error) {
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	}
+
+	switch exporter {
+	case ExporterNone, "":
+	case ExporterStdout:
+		spanExporter, err := stdouttrace.New(stdouttrace.WithWriter(stdout))
+		if err != nil {
+			return nil, fmt.Errorf("stdouttrace.New: %w", err)
+		}
+
+		options = append(options, sdktrace.WithBatcher(spanExporter))
+	case ExporterOtlp:
+		spanExporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+		if err != nil {
+			return nil, fmt.Errorf("otlptracehttp.New: %w", err)
+		}
+
+		options = append(options, sdktrace.WithBatcher(spanExporter))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(options...)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider, nil
+}
+
+// Tracer возвращает трейсер глобального провайдера, поэтому его можно получить до настройки провайдера
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// End завершает спан и помечает его ошибкой, если она есть
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+func UserId(userId fmt.Stringer) attribute.KeyValue {
+	return attribute.String("cart.user_id", userId.String())
+}
+
+func Sku(sku uint64) attribute.KeyValue {
+	return attribute.Int64("cart.sku", int64(sku))
+}
@@ -0,0 +1,29 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTracerProvider(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	provider, err := NewTracerProvider(context.Background(), ExporterStdout, "", "cart", buf)
+	require.NoError(t, err)
+
+	_, span := Tracer("test").Start(context.Background(), "operation")
+	span.End()
+
+	require.NoError(t, provider.Shutdown(context.Background()))
+	require.Contains(t, buf.String(), `"Name":"operation"`)
+
+	provider, err = NewTracerProvider(context.Background(), ExporterNone, "", "cart", buf)
+	require.NoError(t, err)
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	_, err = NewTracerProvider(context.Background(), "jaeger", "", "cart", buf)
+	require.Error(t, err)
+}
@@ -0,0 +1,54 @@
+// Package tracingtest собирает спаны в памяти для проверки трейсов в тестах.
+package tracingtest
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	once     sync.Once
+	recorder *tracetest.SpanRecorder
+)
+
+// Install один раз делает глобальным провайдер с записью спанов в память.
+// Трейсеры, полученные до первой установки провайдера, привязываются только к нему,
+// поэтому все тесты пакета используют общий рекордер и разделяют спаны по trace id.
+func Install() *tracetest.SpanRecorder {
+	once.Do(func() {
+		recorder = tracetest.NewSpanRecorder()
+
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+
+	return recorder
+}
+
+// Spans возвращает завершенные спаны трейса
+func Spans(traceId trace.TraceID) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range Install().Ended() {
+		if span.SpanContext().TraceID() == traceId {
+			spans = append(spans, span)
+		}
+	}
+
+	return spans
+}
+
+// SpanByName возвращает первый завершенный спан трейса с указанным именем
+func SpanByName(traceId trace.TraceID, name string) sdktrace.ReadOnlySpan {
+	for _, span := range Spans(traceId) {
+		if span.Name() == name {
+			return span
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE outbox(
+    id           BIGINT       GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    key          TEXT         NOT NULL,
+    event_type   TEXT         NOT NULL,
+    payload      JSONB        NOT NULL,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX outbox_unpublished_idx ON outbox (id) WHERE published_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE outbox;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+UPDATE cart_items
+SET count = merged.count
+FROM (
+    SELECT MIN(id) AS id, SUM(count) AS count
+    FROM cart_items
+    GROUP BY user_id, sku_id
+    HAVING COUNT(*) > 1
+) AS merged
+WHERE cart_items.id = merged.id;
+
+DELETE FROM cart_items
+USING cart_items AS kept
+WHERE cart_items.user_id = kept.user_id
+    AND cart_items.sku_id = kept.sku_id
+    AND cart_items.id > kept.id;
+
+ALTER TABLE cart_items ADD CONSTRAINT cart_items_user_id_sku_id_key UNIQUE (user_id, sku_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE cart_items DROP CONSTRAINT cart_items_user_id_sku_id_key;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE carts(
+    user_id    UUID         PRIMARY KEY,
+    version    BIGINT       NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+INSERT INTO carts (user_id, version)
+SELECT DISTINCT user_id, 1
+FROM cart_items;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE carts;
+-- +goose StatementEnd
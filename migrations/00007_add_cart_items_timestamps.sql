@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE cart_items
+    ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX cart_items_user_id_updated_at_idx ON cart_items (user_id, updated_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX cart_items_user_id_updated_at_idx;
+
+ALTER TABLE cart_items
+    DROP COLUMN created_at,
+    DROP COLUMN updated_at;
+-- +goose StatementEnd
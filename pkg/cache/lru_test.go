@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[int, string](2)
+
+	c.Set(1, "one", time.Minute)
+	c.Set(2, "two", time.Minute)
+
+	_, ok := c.Get(1)
+	require.True(t, ok)
+
+	c.Set(3, "three", time.Minute)
+
+	_, ok = c.Get(2)
+	require.False(t, ok)
+
+	value, ok := c.Get(1)
+	require.True(t, ok)
+	require.Equal(t, "one", value)
+
+	value, ok = c.Get(3)
+	require.True(t, ok)
+	require.Equal(t, "three", value)
+	require.Equal(t, 2, c.Len())
+}
+
+func TestLRU_Expiration(t *testing.T) {
+	now := time.Now()
+	c := NewLRU[int, string](10)
+	c.now = func() time.Time { return now }
+
+	c.Set(1, "long", time.Minute)
+	c.Set(2, "short", time.Second)
+
+	now = now.Add(2 * time.Second)
+
+	_, ok := c.Get(2)
+	require.False(t, ok)
+
+	value, ok := c.Get(1)
+	require.True(t, ok)
+	require.Equal(t, "long", value)
+	require.Equal(t, 1, c.Len())
+}
+
+func TestLRU_SetOverwrites(t *testing.T) {
+	c := NewLRU[int, string](10)
+
+	c.Set(1, "old", time.Minute)
+	c.Set(1, "new", time.Minute)
+
+	value, ok := c.Get(1)
+	require.True(t, ok)
+	require.Equal(t, "new", value)
+	require.Equal(t, 1, c.Len())
+}
@@ -1,5 +1,7 @@
 package http
 
 type ErrorResponse struct {
-	Message string
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
 }
@@ -1,13 +1,102 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
 )
 
+const internalErrorMessage = "internal error"
+
+type errorRecorder interface {
+	SetError(err error)
+}
+
 func NewErrorResponse(w http.ResponseWriter, statusCode int, message string) error {
+	return writeErrorResponse(w, statusCode, &ErrorResponse{
+		Code:    codeFromStatus(statusCode),
+		Message: message,
+	})
+}
+
+// WriteError отображает ошибку сервиса в код ответа. Текст ошибок без типа клиенту не отдается.
+func WriteError(w http.ResponseWriter, err error) error {
+	statusCode, response := MapError(err)
+
+	// Исходная ошибка не уходит клиенту, но передается в middleware логирования
+	if recorder, ok := w.(errorRecorder); ok {
+		recorder.SetError(err)
+	}
+
+	return writeErrorResponse(w, statusCode, response)
+}
+
+func MapError(err error) (int, *ErrorResponse) {
+	var domainErr *model.Error
+	if !errors.As(err, &domainErr) {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return http.StatusGatewayTimeout, &ErrorResponse{
+				Code:    codeFromStatus(http.StatusGatewayTimeout),
+				Message: "request timed out",
+			}
+		}
+
+		return http.StatusInternalServerError, &ErrorResponse{
+			Code:    codeFromStatus(http.StatusInternalServerError),
+			Message: internalErrorMessage,
+		}
+	}
+
+	response := &ErrorResponse{
+		Code:    domainErr.Code,
+		Message: domainErr.Message,
+		Details: domainErr.Details,
+	}
+
+	var lookupErr *model.ProductsLookupError
+	if errors.As(err, &lookupErr) {
+		response.Details = map[string]any{"skus": lookupErr.Skus()}
+	}
+
+	return statusFromKind(domainErr.Kind), response
+}
+
+func statusFromKind(kind model.ErrorKind) int {
+	switch kind {
+	case model.ErrorKindValidation:
+		return http.StatusBadRequest
+	case model.ErrorKindNotFound:
+		return http.StatusNotFound
+	case model.ErrorKindConflict:
+		return http.StatusConflict
+	case model.ErrorKindUnprocessable:
+		return http.StatusUnprocessableEntity
+	case model.ErrorKindUnavailable:
+		return http.StatusServiceUnavailable
+	case model.ErrorKindUnauthenticated:
+		return http.StatusUnauthorized
+	case model.ErrorKindForbidden:
+		return http.StatusForbidden
+	case model.ErrorKindRateLimited:
+		return http.StatusTooManyRequests
+	case model.ErrorKindPreconditionFailed:
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func codeFromStatus(statusCode int) string {
+	return strings.ToLower(strings.ReplaceAll(http.StatusText(statusCode), " ", "_"))
+}
+
+func writeErrorResponse(w http.ResponseWriter, statusCode int, response *ErrorResponse) error {
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	w.Header().Add("Content-Type", "application/json")
 
-	return json.NewEncoder(w).Encode(&ErrorResponse{Message: message})
+	return json.NewEncoder(w).Encode(response)
 }
@@ -0,0 +1,152 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jva44ka/ozon-simulator-go-cart/internal/domain/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+		code       string
+		message    string
+	}{
+		{
+			name:       "validation",
+			err:        model.NewValidationError("sku", "sku must be greater than zero"),
+			statusCode: http.StatusBadRequest,
+			code:       "validation_failed",
+			message:    "sku must be greater than zero",
+		},
+		{
+			name:       "wrapped not found",
+			err:        fmt.Errorf("productService.GetProductBySku: %w", model.ErrProductNotFound),
+			statusCode: http.StatusNotFound,
+			code:       "product_not_found",
+			message:    "product not found",
+		},
+		{
+			name:       "conflict",
+			err:        fmt.Errorf("cartRepository.AddCartItem :%w", model.ErrCartItemAlreadyExists),
+			statusCode: http.StatusConflict,
+			code:       "cart_item_already_exists",
+			message:    "cart item already exists",
+		},
+		{
+			name:       "upstream unavailable",
+			err:        fmt.Errorf("%w: status 502", model.ErrProductServiceUnavailable),
+			statusCode: http.StatusServiceUnavailable,
+			code:       "product_service_unavailable",
+			message:    "product service unavailable",
+		},
+		{
+			name:       "unauthenticated",
+			err:        fmt.Errorf("%w: bearer token is missing", model.ErrUnauthenticated),
+			statusCode: http.StatusUnauthorized,
+			code:       "unauthenticated",
+			message:    "missing or invalid access token",
+		},
+		{
+			name:       "forbidden",
+			err:        fmt.Errorf("%w: token subject other", model.ErrForbidden),
+			statusCode: http.StatusForbidden,
+			code:       "forbidden",
+			message:    "access to another user's cart is forbidden",
+		},
+		{
+			name:       "rate limited",
+			err:        fmt.Errorf("%w: user limit", model.ErrRateLimited),
+			statusCode: http.StatusTooManyRequests,
+			code:       "rate_limited",
+			message:    "too many requests",
+		},
+		{
+			name:       "precondition failed",
+			err:        fmt.Errorf("checkCartVersion: %w", model.NewCartVersionMismatchError(3)),
+			statusCode: http.StatusPreconditionFailed,
+			code:       "cart_version_mismatch",
+			message:    "cart was modified by another request",
+		},
+		{
+			name:       "timeout",
+			err:        fmt.Errorf("client.Do: %w", context.DeadlineExceeded),
+			statusCode: http.StatusGatewayTimeout,
+			code:       "gateway_timeout",
+			message:    "request timed out",
+		},
+		{
+			name:       "internal error is not leaked",
+			err:        errors.New("pgx: connection refused"),
+			statusCode: http.StatusInternalServerError,
+			code:       "internal_server_error",
+			message:    "internal error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			require.NoError(t, WriteError(w, tt.err))
+			require.Equal(t, tt.statusCode, w.Code)
+			require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+			response := ErrorResponse{}
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+			require.Equal(t, tt.code, response.Code)
+			require.Equal(t, tt.message, response.Message)
+		})
+	}
+}
+
+func TestWriteError_ProductsLookupDetails(t *testing.T) {
+	err := fmt.Errorf("productService.GetProductsBySkus :%w", &model.ProductsLookupError{
+		Errors: map[uint64]error{
+			5: model.ErrProductNotFound,
+			3: model.ErrProductNotFound,
+		},
+	})
+
+	w := httptest.NewRecorder()
+	require.NoError(t, WriteError(w, err))
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	response := ErrorResponse{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	require.Equal(t, "product_not_found", response.Code)
+	require.Equal(t, []any{3.0, 5.0}, response.Details["skus"])
+}
+
+func TestWriteError_CartLimitDetails(t *testing.T) {
+	err := fmt.Errorf("cartService.AddProduct: %w", model.NewCartLimitExceededError(model.CartLimitUnitsPerSku, 10, 12))
+
+	w := httptest.NewRecorder()
+	require.NoError(t, WriteError(w, err))
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	response := ErrorResponse{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	require.Equal(t, "cart_limit_exceeded", response.Code)
+	require.Equal(t, "max_units_per_sku", response.Details["limit"])
+	require.Equal(t, 10.0, response.Details["max"])
+	require.Equal(t, 12.0, response.Details["requested"])
+}
+
+func TestNewErrorResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	require.NoError(t, NewErrorResponse(w, http.StatusBadRequest, "user_id must be valid uuid"))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"code":"bad_request","message":"user_id must be valid uuid"}`, w.Body.String())
+}
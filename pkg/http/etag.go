@@ -0,0 +1,45 @@
+package http
+
+import (
+	"strconv"
+	"strings"
+)
+
+const weakETagPrefix = "W/"
+
+func FormatETag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// ParseETags разбирает значение If-Match или If-None-Match в список версий. matchAny равен true для "*".
+// Слабые теги учитываются только при allowWeak, нераспознанные теги пропускаются.
+func ParseETags(header string, allowWeak bool) (versions []uint64, matchAny bool) {
+	versions = make([]uint64, 0, 1)
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return versions, true
+		}
+
+		if strings.HasPrefix(tag, weakETagPrefix) {
+			if !allowWeak {
+				continue
+			}
+			tag = strings.TrimPrefix(tag, weakETagPrefix)
+		}
+
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+
+		version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		versions = append(versions, version)
+	}
+
+	return versions, false
+}
@@ -0,0 +1,35 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseETags(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		allowWeak bool
+		versions  []uint64
+		matchAny  bool
+	}{
+		{name: "single", header: `"3"`, versions: []uint64{3}},
+		{name: "list", header: `"3", "5"`, versions: []uint64{3, 5}},
+		{name: "any", header: `*`, versions: []uint64{}, matchAny: true},
+		{name: "weak ignored for strong comparison", header: `W/"3"`, versions: []uint64{}},
+		{name: "weak allowed", header: `W/"3"`, allowWeak: true, versions: []uint64{3}},
+		{name: "garbage", header: `3, "abc"`, versions: []uint64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versions, matchAny := ParseETags(tt.header, tt.allowWeak)
+			require.Equal(t, tt.versions, versions)
+			require.Equal(t, tt.matchAny, matchAny)
+		})
+	}
+
+	versions, _ := ParseETags(FormatETag(42), false)
+	require.Equal(t, []uint64{42}, versions)
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// KeyedLimiter — набор token bucket по ключу. Бакеты, к которым не обращались дольше idleTtl,
+// удаляются, поэтому память ограничена числом активных ключей.
+type KeyedLimiter struct {
+	mutex     sync.Mutex
+	limit     rate.Limit
+	burst     int
+	idleTtl   time.Duration
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func NewKeyedLimiter(rps float64, burst int, idleTtl time.Duration) *KeyedLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	// Бакет удаляется не раньше, чем успеет наполниться, иначе удаление сбрасывало бы ограничение
+	if rps > 0 {
+		if refill := time.Duration(float64(burst) / rps * float64(time.Second)); idleTtl < refill {
+			idleTtl = refill
+		}
+	}
+
+	return &KeyedLimiter{
+		limit:   rate.Limit(rps),
+		burst:   burst,
+		idleTtl: idleTtl,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Reserve резервирует токен для ключа на момент now. Резервирование с задержкой нужно отменить через CancelAt.
+func (l *KeyedLimiter) Reserve(key string, now time.Time) *rate.Reservation {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if now.Sub(l.lastSweep) >= l.idleTtl {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	return b.limiter.ReserveN(now, 1)
+}
+
+func (l *KeyedLimiter) Len() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return len(l.buckets)
+}
+
+func (l *KeyedLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= l.idleTtl {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.lastSweep = now
+}
@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyedLimiter_Reserve(t *testing.T) {
+	limiter := NewKeyedLimiter(1, 2, time.Minute)
+	now := time.Now()
+
+	require.Zero(t, limiter.Reserve("first", now).DelayFrom(now))
+	require.Zero(t, limiter.Reserve("first", now).DelayFrom(now))
+	require.Equal(t, time.Second, limiter.Reserve("first", now).DelayFrom(now))
+
+	// У другого ключа собственный бакет
+	require.Zero(t, limiter.Reserve("second", now).DelayFrom(now))
+}
+
+func TestKeyedLimiter_EvictsIdleBuckets(t *testing.T) {
+	limiter := NewKeyedLimiter(10, 1, time.Minute)
+	now := time.Now()
+
+	limiter.Reserve("first", now)
+	limiter.Reserve("second", now.Add(30*time.Second))
+	require.Equal(t, 2, limiter.Len())
+
+	limiter.Reserve("third", now.Add(time.Minute))
+	require.Equal(t, 2, limiter.Len())
+
+	limiter.Reserve("third", now.Add(2*time.Minute))
+	require.Equal(t, 1, limiter.Len())
+}
+
+func TestKeyedLimiter_IdleTtlNotShorterThanRefill(t *testing.T) {
+	limiter := NewKeyedLimiter(1, 10, time.Second)
+
+	require.Equal(t, 10*time.Second, limiter.idleTtl)
+}